@@ -2,12 +2,14 @@ module bananascript
 
 go 1.19
 
-require github.com/gookit/color v1.5.2
+require (
+	github.com/google/go-cmp v0.5.9
+	github.com/gookit/color v1.5.2
+	gotest.tools v2.2.0+incompatible
+)
 
 require (
-	github.com/google/go-cmp v0.5.9 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/xo/terminfo v0.0.0-20210125001918-ca9a967f8778 // indirect
 	golang.org/x/sys v0.0.0-20210330210617-4fbd30eecc44 // indirect
-	gotest.tools v2.2.0+incompatible // indirect
 )
@@ -4,15 +4,24 @@ import (
 	"bananascript/src/evaluator"
 	"bananascript/src/types"
 	"fmt"
+	"io"
 	"math"
+	"math/bits"
+	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type BuiltinFunction struct {
-	Executor     func(evaluator.Object, []evaluator.Object) evaluator.Object
-	This         evaluator.Object
-	FunctionType types.Type
+	Executor       func(evaluator.Object, []evaluator.Object) evaluator.Object
+	This           evaluator.Object
+	BoundArguments []evaluator.Object
+	FunctionType   types.Type
+	// used instead of Executor if set and the caller's environment is known
+	ScopedExecutor func(*evaluator.Environment, []evaluator.Object) evaluator.Object
 }
 
 func (builtinFunction *BuiltinFunction) Type() types.Type {
@@ -20,7 +29,14 @@ func (builtinFunction *BuiltinFunction) Type() types.Type {
 }
 
 func (builtinFunction *BuiltinFunction) Execute(arguments []evaluator.Object) evaluator.Object {
-	return builtinFunction.Executor(builtinFunction.This, arguments)
+	return builtinFunction.Executor(builtinFunction.This, evaluator.BindArguments(builtinFunction.BoundArguments, arguments))
+}
+
+func (builtinFunction *BuiltinFunction) ExecuteInScope(environment *evaluator.Environment, arguments []evaluator.Object) evaluator.Object {
+	if builtinFunction.ScopedExecutor == nil {
+		return builtinFunction.Execute(arguments)
+	}
+	return builtinFunction.ScopedExecutor(environment, evaluator.BindArguments(builtinFunction.BoundArguments, arguments))
 }
 
 func (builtinFunction *BuiltinFunction) With(object evaluator.Object) evaluator.Function {
@@ -29,10 +45,34 @@ func (builtinFunction *BuiltinFunction) With(object evaluator.Object) evaluator.
 	return &newFunction
 }
 
+func (builtinFunction *BuiltinFunction) Bind(argument evaluator.Object) evaluator.Function {
+	newFunction := *builtinFunction
+	newFunction.BoundArguments = evaluator.BindArguments(builtinFunction.BoundArguments, []evaluator.Object{argument})
+	newFunction.FunctionType = builtinFunction.FunctionType.(*types.Function).Bind()
+	return &newFunction
+}
+
+// builtins are equal if they run the same code and are bound to the same object
+func (builtinFunction *BuiltinFunction) Equals(other evaluator.Object) bool {
+	object, isBuiltin := other.(*BuiltinFunction)
+	return isBuiltin && reflect.ValueOf(builtinFunction.Executor).Pointer() == reflect.ValueOf(object.Executor).Pointer() &&
+		evaluator.ObjectsEqual(builtinFunction.This, object.This) &&
+		evaluator.ArgumentsEqual(builtinFunction.BoundArguments, object.BoundArguments)
+}
+
 func (builtinFunction *BuiltinFunction) ToString() string {
 	return "[Function]"
 }
 
+// time source of now and sleep, can be replaced for deterministic tests
+var (
+	Now   = time.Now
+	Sleep = time.Sleep
+)
+
+// where print, println, debug and prompt write to
+var Output io.Writer = os.Stdout
+
 var anyBuiltin = &types.Iface{
 	Members: make(map[string]types.Type),
 }
@@ -41,26 +81,172 @@ var builtinTypes = map[string]types.Type{
 	"any": anyBuiltin,
 }
 
+// a function type that picks the first of the given signatures matching the argument types
+func overloaded(signature string, overloads ...*types.Function) *types.Generic {
+	return &types.Generic{
+		Signature: signature,
+		Resolve: func(argumentTypes []types.Type, context *types.Context) (*types.Function, error) {
+		overloads:
+			for _, overload := range overloads {
+				if len(overload.ParameterTypes) != len(argumentTypes) {
+					continue
+				}
+				for i, parameterType := range overload.ParameterTypes {
+					if !parameterType.IsAssignable(argumentTypes[i], context) {
+						continue overloads
+					}
+				}
+				return overload, nil
+			}
+			typeNames := make([]string, len(argumentTypes))
+			for i, argumentType := range argumentTypes {
+				typeNames[i] = argumentType.ToString()
+			}
+			return nil, fmt.Errorf("No signature of %s matches (%s)", signature, strings.Join(typeNames, ", "))
+		},
+	}
+}
+
+// a function type returning its argument, so print and println can be used inside of expressions
+var passThrough = &types.Generic{
+	Signature: "fn(T) T",
+	Resolve: func(argumentTypes []types.Type, _ *types.Context) (*types.Function, error) {
+		if len(argumentTypes) != 1 {
+			return &types.Function{ParameterTypes: []types.Type{anyBuiltin}, ReturnType: &types.Void{}}, nil
+		}
+		return &types.Function{ParameterTypes: []types.Type{anyBuiltin}, ReturnType: argumentTypes[0]}, nil
+	},
+}
+
+// a function type with the given parameters followed by any amount of arguments of any type
+func variadic(signature string, parameterTypes []types.Type, returnType types.Type) *types.Generic {
+	return &types.Generic{
+		Signature: signature,
+		Resolve: func(argumentTypes []types.Type, _ *types.Context) (*types.Function, error) {
+			resolvedTypes := append([]types.Type{}, parameterTypes...)
+			for len(resolvedTypes) < len(argumentTypes) {
+				resolvedTypes = append(resolvedTypes, anyBuiltin)
+			}
+			return &types.Function{ParameterTypes: resolvedTypes, ReturnType: returnType}, nil
+		},
+	}
+}
+
+// replaces the verbs %s (any value), %q (quoted like debug), %d (int), %f (int or float) and %% in the format string
+func format(formatString string, arguments []evaluator.Object) evaluator.Object {
+	var result strings.Builder
+	runes := []rune(formatString)
+	next := 0
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' {
+			result.WriteRune(runes[i])
+			continue
+		}
+		i++
+		if i == len(runes) {
+			return evaluator.NewError("Format string ends with '%%'")
+		}
+		verb := runes[i]
+		if verb == '%' {
+			result.WriteRune('%')
+			continue
+		}
+		if verb != 's' && verb != 'q' && verb != 'd' && verb != 'f' {
+			return evaluator.NewError("Unknown format verb %%%c", verb)
+		}
+		if next == len(arguments) {
+			return evaluator.NewError("Missing argument for %%%c", verb)
+		}
+		argument := arguments[next]
+		next++
+		switch verb {
+		case 's':
+			result.WriteString(argument.ToString())
+		case 'q':
+			result.WriteString(evaluator.Inspect(argument))
+		case 'd':
+			if _, isInteger := argument.(*evaluator.IntegerObject); !isInteger {
+				return evaluator.NewError("%%d needs an int, got '%s'", argument.Type().ToString())
+			}
+			result.WriteString(argument.ToString())
+		case 'f':
+			switch argument.(type) {
+			case *evaluator.IntegerObject, *evaluator.FloatObject:
+				result.WriteString(strconv.FormatFloat(toFloat(argument), 'f', -1, 64))
+			default:
+				return evaluator.NewError("%%f needs an int or float, got '%s'", argument.Type().ToString())
+			}
+		}
+	}
+	if next < len(arguments) {
+		return evaluator.NewError("Too many arguments for format string (%d vs %d)", len(arguments), next)
+	}
+	return &evaluator.StringObject{Value: result.String()}
+}
+
+func toFloat(object evaluator.Object) float64 {
+	if integer, isInteger := object.(*evaluator.IntegerObject); isInteger {
+		return float64(integer.Value)
+	}
+	return object.(*evaluator.FloatObject).Value
+}
+
+// math builtins fail instead of returning NaN or Infinity for finite arguments outside their domain
+func realResult(name string, result float64, arguments []evaluator.Object) evaluator.Object {
+	argumentStrings := make([]string, len(arguments))
+	for i, argument := range arguments {
+		value := toFloat(argument)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return &evaluator.FloatObject{Value: result}
+		}
+		argumentStrings[i] = argument.ToString()
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return evaluator.NewError("%s(%s) has no finite real result", name, strings.Join(argumentStrings, ", "))
+	}
+	return &evaluator.FloatObject{Value: result}
+}
+
+// rounds to the given number of decimal places, negative digits round to tens, hundreds and so on
+func roundDigits(value float64, digits int64) float64 {
+	factor := math.Pow(10, math.Abs(float64(digits)))
+	if digits < 0 {
+		if math.IsInf(factor, 0) {
+			return math.Copysign(0, value)
+		}
+		return math.Round(value/factor) * factor
+	}
+	scaled := value * factor
+	if math.IsInf(scaled, 0) {
+		return value // too many digits to make a difference
+	}
+	return math.Round(scaled) / factor
+}
+
 var builtinObjects = map[types.Type]map[string]evaluator.Object{
 	nil: {
 		"println": &BuiltinFunction{
+			FunctionType: passThrough,
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				_, _ = fmt.Fprintln(Output, arguments[0].ToString())
+				return arguments[0]
+			},
+		},
+		"debug": &BuiltinFunction{
 			FunctionType: &types.Function{
 				ParameterTypes: []types.Type{anyBuiltin},
 				ReturnType:     &types.Void{},
 			},
 			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
-				fmt.Println(arguments[0].ToString())
+				_, _ = fmt.Fprintln(Output, evaluator.Inspect(arguments[0]))
 				return nil
 			},
 		},
 		"print": &BuiltinFunction{
-			FunctionType: &types.Function{
-				ParameterTypes: []types.Type{anyBuiltin},
-				ReturnType:     &types.Void{},
-			},
+			FunctionType: passThrough,
 			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
-				fmt.Print(arguments[0].ToString())
-				return nil
+				_, _ = fmt.Fprint(Output, arguments[0].ToString())
+				return arguments[0]
 			},
 		},
 		"prompt": &BuiltinFunction{
@@ -69,7 +255,7 @@ var builtinObjects = map[types.Type]map[string]evaluator.Object{
 				ReturnType:     &types.String{},
 			},
 			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
-				fmt.Print(arguments[0].ToString())
+				_, _ = fmt.Fprint(Output, arguments[0].ToString())
 				var input string
 				_, err := fmt.Scanln(&input)
 				if err != nil {
@@ -93,6 +279,249 @@ var builtinObjects = map[types.Type]map[string]evaluator.Object{
 				return &evaluator.IntegerObject{Value: min}
 			},
 		},
+		"assert": &BuiltinFunction{
+			// the parser passes the source of the condition as message if there is none
+			FunctionType: overloaded("fn(bool) void | fn(bool, string) void",
+				&types.Function{ParameterTypes: []types.Type{&types.Bool{}}, ReturnType: &types.Void{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Bool{}, &types.String{}}, ReturnType: &types.Void{}},
+			),
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				if arguments[0].(*evaluator.BooleanObject).Value {
+					return nil
+				}
+				if len(arguments) > 1 {
+					return evaluator.NewError("Assertion failed: %s", arguments[1].ToString())
+				}
+				return evaluator.NewError("Assertion failed")
+			},
+		},
+		"exit": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Int{}},
+				ReturnType:     &types.Never{}, // execution never continues after the call
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return &evaluator.ExitObject{Code: arguments[0].(*evaluator.IntegerObject).Value}
+			},
+		},
+		"now": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{},
+				ReturnType:     &types.Int{},
+			},
+			Executor: func(_ evaluator.Object, _ []evaluator.Object) evaluator.Object {
+				return &evaluator.IntegerObject{Value: Now().UnixMilli()}
+			},
+		},
+		"sleep": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Int{}},
+				ReturnType:     &types.Void{},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				milliseconds := arguments[0].(*evaluator.IntegerObject).Value
+				if milliseconds < 0 {
+					return evaluator.NewError("Cannot sleep for a negative duration (%d)", milliseconds)
+				}
+				Sleep(time.Duration(milliseconds) * time.Millisecond)
+				return nil
+			},
+		},
+		"fromCharCode": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Int{}},
+				ReturnType:     &types.String{},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				code := arguments[0].(*evaluator.IntegerObject).Value
+				if code < 0 || code > utf8.MaxRune || !utf8.ValidRune(rune(code)) {
+					return evaluator.NewError("Invalid character code %d", code)
+				}
+				return &evaluator.StringObject{Value: string(rune(code))}
+			},
+		},
+		"format": &BuiltinFunction{
+			FunctionType: variadic("fn(string, ...) string", []types.Type{&types.String{}}, &types.String{}),
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return format(arguments[0].ToString(), arguments[1:])
+			},
+		},
+		"hasMember": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{anyBuiltin, &types.String{}},
+				ReturnType:     &types.Bool{},
+			},
+			Executor: func(_ evaluator.Object, _ []evaluator.Object) evaluator.Object {
+				return evaluator.NewError("hasMember can only be called from a script")
+			},
+			// members are looked up like in a member access at the call site, so extensions in scope count
+			ScopedExecutor: func(environment *evaluator.Environment, arguments []evaluator.Object) evaluator.Object {
+				name := arguments[1].(*evaluator.StringObject).Value
+				_, exists := environment.GetTypeMember(arguments[0], arguments[0].Type(), name)
+				return &evaluator.BooleanObject{Value: exists}
+			},
+		},
+		"toHex": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Int{}},
+				ReturnType:     &types.String{},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return &evaluator.StringObject{Value: strconv.FormatInt(arguments[0].(*evaluator.IntegerObject).Value, 16)}
+			},
+		},
+		"toBin": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Int{}},
+				ReturnType:     &types.String{},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return &evaluator.StringObject{Value: strconv.FormatInt(arguments[0].(*evaluator.IntegerObject).Value, 2)}
+			},
+		},
+		"popcount": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Int{}},
+				ReturnType:     &types.Int{},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				// negative numbers are counted in their 64 bit two's complement
+				count := bits.OnesCount64(uint64(arguments[0].(*evaluator.IntegerObject).Value))
+				return &evaluator.IntegerObject{Value: int64(count)}
+			},
+		},
+		"Infinity": &evaluator.FloatObject{Value: math.Inf(1)},
+		"NaN":      &evaluator.FloatObject{Value: math.NaN()},
+		"isNaN": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Float{}},
+				ReturnType:     &types.Bool{},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return &evaluator.BooleanObject{Value: math.IsNaN(arguments[0].(*evaluator.FloatObject).Value)}
+			},
+		},
+		"abs": &BuiltinFunction{
+			FunctionType: overloaded("fn(int | float) int | float",
+				&types.Function{ParameterTypes: []types.Type{&types.Int{}}, ReturnType: &types.Int{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Float{}}, ReturnType: &types.Float{}},
+			),
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				switch argument := arguments[0].(type) {
+				case *evaluator.IntegerObject:
+					if argument.Value < 0 {
+						return &evaluator.IntegerObject{Value: -argument.Value}
+					}
+					return &evaluator.IntegerObject{Value: argument.Value}
+				default:
+					return &evaluator.FloatObject{Value: math.Abs(argument.(*evaluator.FloatObject).Value)}
+				}
+			},
+		},
+		"sign": &BuiltinFunction{
+			FunctionType: overloaded("fn(int | float) int",
+				&types.Function{ParameterTypes: []types.Type{&types.Int{}}, ReturnType: &types.Int{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Float{}}, ReturnType: &types.Int{}},
+			),
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				var value float64
+				switch argument := arguments[0].(type) {
+				case *evaluator.IntegerObject:
+					value = float64(argument.Value)
+				default:
+					value = argument.(*evaluator.FloatObject).Value
+				}
+				switch {
+				case value > 0:
+					return &evaluator.IntegerObject{Value: 1}
+				case value < 0:
+					return &evaluator.IntegerObject{Value: -1}
+				default:
+					return &evaluator.IntegerObject{Value: 0}
+				}
+			},
+		},
+		"sqrt": &BuiltinFunction{
+			FunctionType: overloaded("fn(int | float) float",
+				&types.Function{ParameterTypes: []types.Type{&types.Int{}}, ReturnType: &types.Float{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Float{}}, ReturnType: &types.Float{}},
+			),
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return realResult("sqrt", math.Sqrt(toFloat(arguments[0])), arguments)
+			},
+		},
+		"pow": &BuiltinFunction{
+			FunctionType: overloaded("fn(int | float, int | float) float",
+				&types.Function{ParameterTypes: []types.Type{&types.Int{}, &types.Int{}}, ReturnType: &types.Float{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Int{}, &types.Float{}}, ReturnType: &types.Float{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Float{}, &types.Int{}}, ReturnType: &types.Float{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Float{}, &types.Float{}}, ReturnType: &types.Float{}},
+			),
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return realResult("pow", math.Pow(toFloat(arguments[0]), toFloat(arguments[1])), arguments)
+			},
+		},
+		"round": &BuiltinFunction{
+			FunctionType: overloaded("fn(int | float) float | fn(int | float, int) float",
+				&types.Function{ParameterTypes: []types.Type{&types.Int{}}, ReturnType: &types.Float{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Float{}}, ReturnType: &types.Float{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Int{}, &types.Int{}}, ReturnType: &types.Float{}},
+				&types.Function{ParameterTypes: []types.Type{&types.Float{}, &types.Int{}}, ReturnType: &types.Float{}},
+			),
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				value := toFloat(arguments[0])
+				digits := int64(0)
+				if len(arguments) > 1 {
+					digits = arguments[1].(*evaluator.IntegerObject).Value
+				}
+				return &evaluator.FloatObject{Value: roundDigits(value, digits)}
+			},
+		},
+		"partial": &BuiltinFunction{
+			FunctionType: &types.Generic{
+				Signature: "fn(fn(T, ...) R, T) fn(...) R",
+				Resolve: func(argumentTypes []types.Type, context *types.Context) (*types.Function, error) {
+					if len(argumentTypes) != 2 {
+						return nil, fmt.Errorf("Mismatching amount of arguments (%d vs 2)", len(argumentTypes))
+					}
+					functionType, isFunction := argumentTypes[0].(*types.Function)
+					if !isFunction || len(functionType.ParameterTypes) == 0 {
+						return nil, fmt.Errorf("Cannot partially apply '%s'", argumentTypes[0].ToString())
+					}
+					return &types.Function{
+						ParameterTypes: []types.Type{functionType, functionType.ParameterTypes[0]},
+						ReturnType:     functionType.Bind(),
+					}, nil
+				},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return arguments[0].(evaluator.Function).Bind(arguments[1])
+			},
+		},
+		"arity": &BuiltinFunction{
+			FunctionType: &types.Generic{
+				Signature: "fn(fn(...) R) int",
+				Resolve: func(argumentTypes []types.Type, context *types.Context) (*types.Function, error) {
+					if len(argumentTypes) != 1 {
+						return nil, fmt.Errorf("Mismatching amount of arguments (%d vs 1)", len(argumentTypes))
+					}
+					switch argumentTypes[0].(type) {
+					case *types.Function, *types.Generic:
+						return &types.Function{ParameterTypes: argumentTypes, ReturnType: &types.Int{}}, nil
+					default:
+						return nil, fmt.Errorf("Cannot get the arity of '%s'", argumentTypes[0].ToString())
+					}
+				},
+			},
+			Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				// generic builtins like format or abs accept different amounts of arguments
+				functionType, isFunction := arguments[0].Type().(*types.Function)
+				if !isFunction {
+					return &evaluator.IntegerObject{Value: -1}
+				}
+				return &evaluator.IntegerObject{Value: int64(len(functionType.ParameterTypes))}
+			},
+		},
 		"max": &BuiltinFunction{
 			FunctionType: &types.Function{
 				ParameterTypes: []types.Type{&types.Int{}, &types.Int{}},
@@ -183,6 +612,25 @@ var builtinObjects = map[types.Type]map[string]evaluator.Object{
 				return &evaluator.IntegerObject{Value: int64(len([]rune(this.ToString())))}
 			},
 		},
+		"format": &BuiltinFunction{
+			FunctionType: variadic("fn(...) string", []types.Type{}, &types.String{}),
+			Executor: func(this evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				return format(this.ToString(), arguments)
+			},
+		},
+		"reverse": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{},
+				ReturnType:     &types.String{},
+			},
+			Executor: func(this evaluator.Object, _ []evaluator.Object) evaluator.Object {
+				runes := []rune(this.ToString())
+				for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+					runes[i], runes[j] = runes[j], runes[i]
+				}
+				return &evaluator.StringObject{Value: string(runes)}
+			},
+		},
 		"uppercase": &BuiltinFunction{
 			FunctionType: &types.Function{
 				ParameterTypes: []types.Type{},
@@ -201,6 +649,20 @@ var builtinObjects = map[types.Type]map[string]evaluator.Object{
 				return &evaluator.StringObject{Value: strings.ToLower(this.ToString())}
 			},
 		},
+		"charCodeAt": &BuiltinFunction{
+			FunctionType: &types.Function{
+				ParameterTypes: []types.Type{&types.Int{}},
+				ReturnType:     &types.Int{},
+			},
+			Executor: func(this evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+				runes := []rune(this.ToString())
+				index := arguments[0].(*evaluator.IntegerObject).Value
+				if index < 0 || index >= int64(len(runes)) {
+					return evaluator.NewError("Index %d out of bounds for length %d", index, len(runes))
+				}
+				return &evaluator.IntegerObject{Value: int64(runes[index])}
+			},
+		},
 		"parseInt": &BuiltinFunction{
 			FunctionType: &types.Function{
 				ParameterTypes: []types.Type{},
@@ -215,12 +677,57 @@ var builtinObjects = map[types.Type]map[string]evaluator.Object{
 	},
 }
 
+// the only builtins available in a sandbox, they compute values without any effect on the host like output,
+// input, time or ending the program. Builtins that are added later are not available until they are listed here
+var sandboxBuiltins = map[string]bool{
+	"Infinity":     true,
+	"NaN":          true,
+	"abs":          true,
+	"arity":        true,
+	"ceil":         true,
+	"charCodeAt":   true,
+	"floor":        true,
+	"format":       true,
+	"fromCharCode": true,
+	"hasMember":    true,
+	"isNaN":        true,
+	"length":       true,
+	"lowercase":    true,
+	"max":          true,
+	"min":          true,
+	"parseInt":     true,
+	"partial":      true,
+	"popcount":     true,
+	"pow":          true,
+	"reverse":      true,
+	"round":        true,
+	"sign":         true,
+	"sqrt":         true,
+	"toBin":        true,
+	"toHex":        true,
+	"toString":     true,
+	"uppercase":    true,
+}
+
 func NewContextAndEnvironment() (*types.Context, *evaluator.Environment) {
+	return newContextAndEnvironment(false)
+}
+
+// like NewContextAndEnvironment, but only with the builtins in sandboxBuiltins, so scripts referring to others
+// do not type check
+func NewSandboxContextAndEnvironment() (*types.Context, *evaluator.Environment) {
+	return newContextAndEnvironment(true)
+}
+
+func newContextAndEnvironment(sandbox bool) (*types.Context, *evaluator.Environment) {
 	context := types.NewContext()
+	context.Builtins = true
 	environment := evaluator.NewEnvironment(context)
 	for parentType, builtins := range builtinObjects {
 		for name, builtin := range builtins {
-			if parentType == nil {
+			if sandbox && !sandboxBuiltins[name] {
+				continue
+			} else if parentType == nil {
 				context.DefineMemberType(name, builtin.Type())
 				environment.DefineObject(name, builtin)
 			} else {
@@ -234,3 +741,23 @@ func NewContextAndEnvironment() (*types.Context, *evaluator.Environment) {
 	}
 	return context, environment
 }
+
+// makes a host function available to scripts that are parsed with the given context and evaluated in the given environment
+func Register(context *types.Context, environment *evaluator.Environment, name string, parameterTypes []types.Type,
+	returnType types.Type, function func([]evaluator.Object) evaluator.Object) bool {
+
+	builtin := &BuiltinFunction{
+		FunctionType: &types.Function{
+			ParameterTypes: parameterTypes,
+			ReturnType:     returnType,
+		},
+		Executor: func(_ evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+			return function(arguments)
+		},
+	}
+	if _, ok := context.DefineMemberType(name, builtin.Type()); !ok {
+		return false
+	}
+	environment.DefineObject(name, builtin)
+	return true
+}
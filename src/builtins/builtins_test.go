@@ -0,0 +1,425 @@
+package builtins
+
+import (
+	"bananascript/src/evaluator"
+	"bananascript/src/lexer"
+	"bananascript/src/parser"
+	"bananascript/src/token"
+	"bananascript/src/types"
+	"bytes"
+	"gotest.tools/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCharCodes(t *testing.T) {
+
+	assertObject(t,
+		"\"abc\".charCodeAt(1);",
+		&evaluator.IntegerObject{Value: 98},
+	)
+
+	assertObject(t,
+		"\"你好\".charCodeAt(1);",
+		&evaluator.IntegerObject{Value: 22909},
+	)
+
+	assertObject(t,
+		"fromCharCode(98);",
+		&evaluator.StringObject{Value: "b"},
+	)
+
+	assertObject(t,
+		"fromCharCode(\"🐈\".charCodeAt(0));",
+		&evaluator.StringObject{Value: "🐈"},
+	)
+
+	assertError(t, "\"abc\".charCodeAt(3);")
+	assertError(t, "\"abc\".charCodeAt(-1);")
+	assertError(t, "fromCharCode(-1);")
+	assertError(t, "fromCharCode(55296);")
+	assertError(t, "println(fromCharCode(1114112));")
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		format    string
+		arguments string
+		expected  string
+	}{
+		{"\"%d and %s\"", "1, \"x\"", "1 and x"},
+		{"\"%s|%s|%s\"", "1.5, true, null", "1.5|true|null"},
+		{"\"%q %q\"", "\"a\", 2", "\"a\" 2"},
+		{"\"%f %f\"", "2, 2.25", "2 2.25"},
+		{"\"100%%\"", "", "100%"},
+		{"\"\"", "", ""},
+	}
+
+	for _, c := range cases {
+		separator := ""
+		if c.arguments != "" {
+			separator = ", "
+		}
+		assertObject(t, "format("+c.format+separator+c.arguments+");", &evaluator.StringObject{Value: c.expected})
+		assertObject(t, c.format+".format("+c.arguments+");", &evaluator.StringObject{Value: c.expected})
+	}
+
+	assertError(t, "format(\"%s\", 1, 2);")
+	assertError(t, "\"%s\".format(1, 2);")
+	assertError(t, "\"%d\".format();")
+	assertError(t, "\"%d\".format(1.5);")
+	assertError(t, "\"%f\".format(\"a\");")
+	assertError(t, "\"%x\".format(1);")
+	assertError(t, "\"50%\".format();")
+	assertObject(t, "let f := \"<%s>\".format; f(3);", &evaluator.StringObject{Value: "<3>"})
+	assertParserError(t, "format(1);", "Type 'int' is not assignable to 'string'")
+	assertParserError(t, "format();", "Mismatching amount of arguments (0 vs 1)")
+	assertParserError(t, "let a: int = \"%d\".format(1);", "Type 'string' is not assignable to 'int'")
+}
+
+func TestHasMember(t *testing.T) {
+	assertObject(t, "hasMember(\"a\", \"length\");", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "hasMember(1, \"length\");", &evaluator.BooleanObject{Value: false})
+	assertObject(t, "hasMember(1.5, \"toString\");", &evaluator.BooleanObject{Value: true})
+	assertObject(t, `
+		fn (int)::double() int { return this * 2; }
+		let value: any = 3;
+		hasMember(value, "double") && !hasMember("3", "double");
+	`, &evaluator.BooleanObject{Value: true})
+	assertObject(t, `
+		let inner := {
+			fn (string)::shout() string { return this.uppercase(); }
+			hasMember("a", "shout")
+		};
+		inner && !hasMember("a", "shout");
+	`, &evaluator.BooleanObject{Value: true})
+	assertObject(t, "let check := partial(hasMember, 1); check(\"abs\");", &evaluator.BooleanObject{Value: true})
+	assertParserError(t, "hasMember(1, 2);", "Type 'int' is not assignable to 'string'")
+}
+
+func TestBits(t *testing.T) {
+	assertObject(t, "toHex(255);", &evaluator.StringObject{Value: "ff"})
+	assertObject(t, "toHex(0);", &evaluator.StringObject{Value: "0"})
+	assertObject(t, "toHex(-255);", &evaluator.StringObject{Value: "-ff"})
+	assertObject(t, "toBin(10);", &evaluator.StringObject{Value: "1010"})
+	assertObject(t, "toBin(-10);", &evaluator.StringObject{Value: "-1010"})
+	assertObject(t, "popcount(7);", &evaluator.IntegerObject{Value: 3})
+	assertObject(t, "popcount(0);", &evaluator.IntegerObject{Value: 0})
+	assertObject(t, "popcount(-1);", &evaluator.IntegerObject{Value: 64})
+	assertParserError(t, "toHex(1.5);", "Type 'float' is not assignable to 'int'")
+}
+
+func TestReverse(t *testing.T) {
+	assertObject(t, "\"abc\".reverse();", &evaluator.StringObject{Value: "cba"})
+	assertObject(t, "\"\".reverse();", &evaluator.StringObject{Value: ""})
+	assertObject(t, "\"a🐈b\".reverse();", &evaluator.StringObject{Value: "b🐈a"})
+	assertObject(t, "let a := \"xy\"; let b := a.reverse(); a + b;", &evaluator.StringObject{Value: "xyyx"})
+}
+
+func TestFloatConstants(t *testing.T) {
+	assertObject(t, "1.0 / 0.0 == Infinity;", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "-1.0 / 0.0 == -Infinity;", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "Infinity > 1000000.0;", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "Infinity - Infinity == Infinity;", &evaluator.BooleanObject{Value: false})
+	assertObject(t, "isNaN(0.0 / 0.0);", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "isNaN(Infinity - Infinity);", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "isNaN(1.5);", &evaluator.BooleanObject{Value: false})
+	assertObject(t, "NaN == NaN;", &evaluator.BooleanObject{Value: false})
+	assertObject(t, "NaN != NaN;", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "NaN < 1.0 || NaN > 1.0 || NaN <= NaN;", &evaluator.BooleanObject{Value: false})
+	assertParserError(t, "Infinity = 1.0;", "Cannot assign to builtin 'Infinity'")
+	assertParserError(t, "NaN = 0.0;", "Cannot assign to builtin 'NaN'")
+	assertParserError(t, "Infinity += 1.0;", "Cannot assign to builtin 'Infinity'")
+	assertParserError(t, "NaN++;", "Cannot assign to builtin 'NaN'")
+	assertParserError(t, "fn f() { Infinity = 1.0; }", "Cannot assign to builtin 'Infinity'")
+	assertParserError(t, "println = print;", "Cannot assign to builtin 'println'")
+	assertObject(t, "fn f() float { let Infinity := 1.0; Infinity = 2.0; return Infinity; } f();", &evaluator.FloatObject{Value: 2})
+}
+
+func TestBuiltinEquals(t *testing.T) {
+	assertObject(t, "println == println;", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "println == print;", &evaluator.BooleanObject{Value: false})
+	assertObject(t, "\"a\".length == \"a\".length;", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "\"a\".length == \"b\".length;", &evaluator.BooleanObject{Value: false})
+	assertObject(t, "\"a\".length == \"a\".uppercase;", &evaluator.BooleanObject{Value: false})
+}
+
+func TestOverloads(t *testing.T) {
+	assertObject(t, "abs(-3);", &evaluator.IntegerObject{Value: 3})
+	assertObject(t, "abs(3);", &evaluator.IntegerObject{Value: 3})
+	assertObject(t, "abs(-3.0);", &evaluator.FloatObject{Value: 3})
+	assertObject(t, "let a: int = abs(-2) + 1; a;", &evaluator.IntegerObject{Value: 3})
+	assertObject(t, "sign(-7);", &evaluator.IntegerObject{Value: -1})
+	assertObject(t, "sign(0);", &evaluator.IntegerObject{Value: 0})
+	assertObject(t, "sign(0.5);", &evaluator.IntegerObject{Value: 1})
+	assertObject(t, "sign(-0.5) * 2;", &evaluator.IntegerObject{Value: -2})
+
+	assertParserError(t, "let a: int = abs(-3.0);", "Type 'float' is not assignable to 'int'")
+	assertParserError(t, "abs(\"a\");", "No signature of fn(int | float) int | float matches (string)")
+	assertParserError(t, "sign(1, 2);", "No signature of fn(int | float) int matches (int, int)")
+}
+
+func TestMathDomain(t *testing.T) {
+	assertObject(t, "sqrt(4);", &evaluator.FloatObject{Value: 2})
+	assertObject(t, "sqrt(2.25);", &evaluator.FloatObject{Value: 1.5})
+	assertObject(t, "sqrt(0);", &evaluator.FloatObject{Value: 0})
+	assertObject(t, "pow(2, 10);", &evaluator.FloatObject{Value: 1024})
+	assertError(t, "sqrt(-1);")
+	assertError(t, "pow(-8, 1.0 / 3.0);")
+	assertObject(t, "pow(-8.0, 2);", &evaluator.FloatObject{Value: 64})
+	assertObject(t, "isNaN(sqrt(NaN));", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "pow(Infinity, 2) == Infinity;", &evaluator.BooleanObject{Value: true})
+	assertError(t, "pow(0, -1);")
+	assertError(t, "pow(10.0, 400);")
+	assert.Equal(t, eval(t, "sqrt(-2.5);").(*evaluator.ErrorObject).Message, "sqrt(-2.5) has no finite real result")
+	assertParserError(t, "sqrt(\"4\");", "No signature of fn(int | float) float matches (string)")
+}
+
+func TestRound(t *testing.T) {
+	assertObject(t, "round(3.14159, 2);", &evaluator.FloatObject{Value: 3.14})
+	assertObject(t, "round(1234.5, -2);", &evaluator.FloatObject{Value: 1200})
+	assertObject(t, "round(2.5);", &evaluator.FloatObject{Value: 3})
+	assertObject(t, "round(-2.45, 1);", &evaluator.FloatObject{Value: -2.5})
+	assertObject(t, "round(1.5, 400);", &evaluator.FloatObject{Value: 1.5})
+	assertObject(t, "round(1.5, -400);", &evaluator.FloatObject{Value: 0})
+	assertObject(t, "round(1234, -2);", &evaluator.FloatObject{Value: 1200})
+	assertObject(t, "round(7);", &evaluator.FloatObject{Value: 7})
+	assertParserError(t, "round(1.5, 2.0);", "No signature of fn(int | float) float | fn(int | float, int) float matches (float, float)")
+	assertParserError(t, "round(\"1\");", "No signature of fn(int | float) float | fn(int | float, int) float matches (string)")
+}
+
+func TestExit(t *testing.T) {
+	assertObject(t, "let a := 1; exit(2); a = 3;", &evaluator.ExitObject{Code: 2})
+	assertObject(t, "fn f() int { exit(5); } let a := f() + 1; a;", &evaluator.ExitObject{Code: 5})
+	assertObject(t, "let i := 0; while true { i++; if i == 3 { exit(i); } }", &evaluator.ExitObject{Code: 3})
+	assertObject(t, "let a := if true { exit(1) } else { 2 }; a;", &evaluator.ExitObject{Code: 1})
+	assertParserError(t, "fn f() int { exit(1); return 2; }", "Unreachable code")
+	assertObject(t, "fn f(a: bool) int { if a { exit(1); } else { exit(2); } } f(false);", &evaluator.ExitObject{Code: 2})
+	assertParserError(t, "fn f() void { nope(1); }", "Cannot resolve reference to 'nope'")
+	assertParserError(t, "fn f() int { exit(); }", "Mismatching amount of arguments (0 vs 1)")
+	assertParserError(t, "exit(\"a\");", "Type 'string' is not assignable to 'int'")
+}
+
+func TestAssert(t *testing.T) {
+	assertObject(t, "assert(true); assert(1 < 2, \"math\");", nil)
+	assertObject(t, "let x := 0; assert(x > 0);", &evaluator.ErrorObject{
+		Message: "Assertion failed: (x > 0)",
+		Token:   &token.Token{Type: token.Ident, Literal: "assert", Line: 1, Col: 13},
+	})
+	assertObject(t, "assert(false, \"custom\");", &evaluator.ErrorObject{
+		Message: "Assertion failed: custom",
+		Token:   &token.Token{Type: token.Ident, Literal: "assert", Line: 1, Col: 1},
+	})
+	assertObject(t, "fn check() bool { fn assert(condition: bool) bool { return condition; } return assert(false); } check();",
+		&evaluator.BooleanObject{Value: false})
+	assertParserError(t, "assert(1);", "No signature of fn(bool) void | fn(bool, string) void matches (int)")
+	assertParserError(t, "assert(true, 1);", "No signature of fn(bool) void | fn(bool, string) void matches (bool, int)")
+}
+
+func TestTime(t *testing.T) {
+	previousNow, previousSleep := Now, Sleep
+	t.Cleanup(func() {
+		Now, Sleep = previousNow, previousSleep
+	})
+	current := time.UnixMilli(1000)
+	Now = func() time.Time { return current }
+	Sleep = func(duration time.Duration) { current = current.Add(duration) }
+
+	assertObject(t, "now();", &evaluator.IntegerObject{Value: 1000})
+	assertObject(t, "let start := now(); sleep(250); now() - start;", &evaluator.IntegerObject{Value: 250})
+	assertError(t, "sleep(-1);")
+	assertParserError(t, "sleep(1.5);", "Type 'float' is not assignable to 'int'")
+}
+
+func TestOutput(t *testing.T) {
+	output := captureOutput(t)
+
+	eval(t, "print(\"hi\");")
+	assert.Equal(t, output.String(), "hi")
+
+	output.Reset()
+	eval(t, "debug(\"hi\"); debug(\"a\\nb\"); debug(1.5); println(\"hi\");")
+	assert.Equal(t, output.String(), "\"hi\"\n\"a\\nb\"\n1.5\nhi\n")
+}
+
+func TestPrintPassThrough(t *testing.T) {
+	output := captureOutput(t)
+
+	assertObject(t, "print(5);", &evaluator.IntegerObject{Value: 5})
+	assert.Equal(t, output.String(), "5")
+
+	output.Reset()
+	assertObject(t, "let x: string = println(\"a\" + \"b\"); x;", &evaluator.StringObject{Value: "ab"})
+	assert.Equal(t, output.String(), "ab\n")
+
+	output.Reset()
+	assertObject(t, "print(2) * println(3);", &evaluator.IntegerObject{Value: 6})
+	assert.Equal(t, output.String(), "23\n")
+
+	assertParserError(t, "let x: int = print(\"a\");", "Type 'string' is not assignable to 'int'")
+	assertParserError(t, "print(1, 2);", "Mismatching amount of arguments (2 vs 1)")
+	assertParserError(t, "fn f(a: int) int { return a; } println(f());", "Mismatching amount of arguments (0 vs 1)")
+
+	// arguments of generic builtins are only checked once, nesting them does not take exponential time
+	output.Reset()
+	nested := strings.Repeat("println(abs(", 30) + "-1" + strings.Repeat("))", 30) + ";"
+	assertObject(t, nested, &evaluator.IntegerObject{Value: 1})
+	assert.Equal(t, output.String(), strings.Repeat("1\n", 30))
+}
+
+func TestArity(t *testing.T) {
+	assertObject(t, "fn add(a: int, b: int) int { return a + b; } arity(add);", &evaluator.IntegerObject{Value: 2})
+	assertObject(t, "fn f() {} arity(f);", &evaluator.IntegerObject{Value: 0})
+	assertObject(t, "fn add(a: int, b: int) int { return a + b; } arity(partial(add, 1));", &evaluator.IntegerObject{Value: 1})
+	assertObject(t, "fn (int)::plus(other: int) int { return this + other; } arity((1).plus);", &evaluator.IntegerObject{Value: 1})
+	assertObject(t, "arity(max);", &evaluator.IntegerObject{Value: 2})
+	assertObject(t, "arity(\"a\".length);", &evaluator.IntegerObject{Value: 0})
+	assertObject(t, "arity(format);", &evaluator.IntegerObject{Value: -1})
+	assertObject(t, "arity(\"%s\".format);", &evaluator.IntegerObject{Value: -1})
+	assertParserError(t, "arity(1);", "Cannot get the arity of 'int'")
+	assertParserError(t, "arity();", "Mismatching amount of arguments (0 vs 1)")
+}
+
+func TestPartial(t *testing.T) {
+	assertObject(t, `
+		fn subtract(a: int, b: int) int { return a - b; }
+		let fromTen := partial(subtract, 10);
+		fromTen(3);
+	`, &evaluator.IntegerObject{Value: 7})
+
+	assertObject(t, `
+		fn join(a: string, b: string, c: string) string { return a + b + c; }
+		let withPrefix: fn(string, string) string = partial(join, "<");
+		let wrapped: fn(string) string = partial(withPrefix, "x");
+		wrapped(">");
+	`, &evaluator.StringObject{Value: "<x>"})
+
+	assertObject(t, "partial(min, 4)(9);", &evaluator.IntegerObject{Value: 4})
+	assertObject(t, "partial(min, 4) == partial(min, 4);", &evaluator.BooleanObject{Value: true})
+	assertObject(t, "partial(min, 4) == partial(min, 5);", &evaluator.BooleanObject{Value: false})
+
+	assertParserError(t, "partial(5, 1);", "Cannot partially apply 'int'")
+	assertParserError(t, "partial(min, \"a\");", "Type 'string' is not assignable to 'int'")
+	assertParserError(t, "partial(min, 1)(2, 3);", "Mismatching amount of arguments (2 vs 1)")
+	assertParserError(t, "fn test() {} partial(test, 1);", "Cannot partially apply 'fn() void'")
+}
+
+func TestShadowBuiltins(t *testing.T) {
+	assertObject(t, "let a := { let min := 5; min };", nil)
+	assertObject(t, "fn f(max: int) int { let print := max; return print; } f(3);", &evaluator.IntegerObject{Value: 3})
+	assertObject(t, "fn f() int { fn min() int { return 1; } return min(); } f();", &evaluator.IntegerObject{Value: 1})
+	assertObject(t, "fn (int)::min() int { return this; } (4).min();", &evaluator.IntegerObject{Value: 4})
+	assertParserError(t, "let min := 5;", "Cannot redefine builtin 'min'")
+	assertParserError(t, "fn println() {}", "Cannot redefine builtin 'println'")
+	assertParserError(t, "let a := 1, print := 2;", "Cannot redefine builtin 'print'")
+	assertParserError(t, "fn (int)::f() {} fn (int)::f() {}", "Cannot redefine 'f'")
+
+	context, _ := NewContextAndEnvironment()
+	program, _ := parser.New(lexer.FromCode("let a := 1;")).ParseProgram(context)
+	_, errors := parser.New(lexer.FromCode("let max := 1;")).ParseProgram(program.Context)
+	assert.Equal(t, len(errors), 1)
+	assert.Equal(t, errors[0].Message, "Cannot redefine builtin 'max'")
+}
+
+func TestSandbox(t *testing.T) {
+	context, environment := NewSandboxContextAndEnvironment()
+	expected := []string{"Infinity", "NaN", "abs", "arity", "format", "fromCharCode", "hasMember", "isNaN", "max", "min",
+		"partial", "popcount", "pow", "round", "sign", "sqrt", "toBin", "toHex"}
+	assert.DeepEqual(t, environment.DefinedNames(), expected)
+
+	sandboxed := make(map[string]bool)
+	for _, name := range expected {
+		sandboxed[name] = true
+	}
+	for name := range builtinObjects[nil] {
+		_, defined := context.GetMemberType(name)
+		assert.Equal(t, defined, sandboxed[name], name)
+	}
+
+	program, errors := parser.New(lexer.FromCode("exit(1);")).ParseProgram(context)
+	assert.Equal(t, len(errors), 1)
+	assert.Equal(t, errors[0].Message, "Cannot resolve reference to 'exit'")
+
+	program, errors = parser.New(lexer.FromCode("let a := abs(-2) + \"ab\".length();")).ParseProgram(context)
+	assert.Equal(t, len(errors), 0)
+	newEnvironment := evaluator.ExtendEnvironment(environment, program.Context)
+	evaluator.EvalStatements(program.Statements, newEnvironment)
+	a, _ := newEnvironment.GetObject("a")
+	assert.DeepEqual(t, a, &evaluator.IntegerObject{Value: 4})
+
+	context, _ = NewContextAndEnvironment()
+	_, defined := context.GetMemberType("exit")
+	assert.Assert(t, defined)
+}
+
+func TestRegister(t *testing.T) {
+
+	context, environment := NewContextAndEnvironment()
+	ok := Register(context, environment, "add", []types.Type{&types.Int{}, &types.Int{}}, &types.Int{},
+		func(arguments []evaluator.Object) evaluator.Object {
+			a := arguments[0].(*evaluator.IntegerObject).Value
+			b := arguments[1].(*evaluator.IntegerObject).Value
+			return &evaluator.IntegerObject{Value: a + b}
+		},
+	)
+	assert.Assert(t, ok)
+	assert.Assert(t, !Register(context, environment, "println", []types.Type{}, &types.Void{},
+		func(arguments []evaluator.Object) evaluator.Object { return nil }))
+
+	theParser := parser.New(lexer.FromCode("let sum := add(2, add(3, 4));"))
+	program, errors := theParser.ParseProgram(context)
+	assert.Equal(t, len(errors), 0)
+
+	newEnvironment := evaluator.ExtendEnvironment(environment, program.Context)
+	evaluator.EvalStatements(program.Statements, newEnvironment)
+	sum, _ := newEnvironment.GetObject("sum")
+	assert.DeepEqual(t, sum, &evaluator.IntegerObject{Value: 9})
+
+	theParser = parser.New(lexer.FromCode("add(1, \"2\");"))
+	_, errors = theParser.ParseProgram(context)
+	assert.Equal(t, len(errors), 1)
+}
+
+// redirects Output to a buffer until the test has finished
+func captureOutput(t *testing.T) *bytes.Buffer {
+	previousOutput := Output
+	t.Cleanup(func() {
+		Output = previousOutput
+	})
+	output := &bytes.Buffer{}
+	Output = output
+	return output
+}
+
+func eval(t *testing.T, input string) evaluator.Object {
+
+	theLexer := lexer.FromCode(input)
+	theParser := parser.New(theLexer)
+
+	context, environment := NewContextAndEnvironment()
+	program, errors := theParser.ParseProgram(context)
+	for _, err := range errors {
+		t.Fatal(err.Message)
+	}
+
+	newEnvironment := evaluator.ExtendEnvironment(environment, program.Context)
+	return evaluator.EvalStatements(program.Statements, newEnvironment)
+}
+
+func assertParserError(t *testing.T, input string, message string) {
+	theParser := parser.New(lexer.FromCode(input))
+	context, _ := NewContextAndEnvironment()
+	_, errors := theParser.ParseProgram(context)
+	assert.Assert(t, len(errors) == 1, input)
+	assert.Equal(t, errors[0].Message, message)
+}
+
+func assertObject(t *testing.T, input string, expected evaluator.Object) {
+	assert.DeepEqual(t, eval(t, input), expected)
+}
+
+func assertError(t *testing.T, input string) {
+	_, isError := eval(t, input).(*evaluator.ErrorObject)
+	assert.Assert(t, isError, input)
+}
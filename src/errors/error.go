@@ -4,13 +4,16 @@ import (
 	"bananascript/src/token"
 	"fmt"
 	"github.com/gookit/color"
+	"strings"
 )
 
+// an error found while lexing or parsing, it implements the error interface so hosts can inspect it
 type ParserError struct {
 	Line    int
 	Col     int
 	File    *string
 	Message string
+	Token   *token.Token // offending token, nil for errors of the lexer
 }
 
 func New(line int, col int, file *string, messageFormat string, args ...interface{}) *ParserError {
@@ -18,11 +21,24 @@ func New(line int, col int, file *string, messageFormat string, args ...interfac
 }
 
 func NewFromToken(token *token.Token, messageFormat string, args ...interface{}) *ParserError {
-	return New(token.Line, token.Col, token.File, messageFormat, args...)
+	err := New(token.Line, token.Col, token.File, messageFormat, args...)
+	err.Token = token
+	return err
+}
+
+func (error *ParserError) Error() string {
+	location := fmt.Sprintf("%d:%d", error.Line, error.Col)
+	if error.File != nil {
+		location = *error.File + ":" + location
+	}
+	return location + ": " + error.Message
 }
 
 func (error *ParserError) PrettyPrint(withSource bool) string {
-	result := color.FgRed.Sprintf("Error: %s", error.Message)
+	return error.withLocation(color.FgRed.Sprintf("Error: %s", error.Message), withSource)
+}
+
+func (error *ParserError) withLocation(result string, withSource bool) string {
 	if withSource {
 		result += "\n\tin "
 		if error.File != nil {
@@ -32,3 +48,33 @@ func (error *ParserError) PrettyPrint(withSource bool) string {
 	}
 	return result
 }
+
+// pretty prints the error followed by the offending source line and a caret under its column
+func FormatError(source string, err *ParserError) string {
+	return withSourceLine(source, err, err.PrettyPrint(true))
+}
+
+// like FormatError, for diagnostics that do not stop the program
+func FormatWarning(source string, err *ParserError) string {
+	return withSourceLine(source, err, err.withLocation(color.FgYellow.Sprintf("Warning: %s", err.Message), true))
+}
+
+func withSourceLine(source string, err *ParserError, result string) string {
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(source), "\n")
+	if err.Line < 1 || err.Line > len(lines) {
+		return result
+	}
+	line := []rune(lines[err.Line-1])
+
+	// keep tabs so that the caret lines up with the source line
+	indentation := make([]rune, 0, err.Col)
+	for i := 0; i < err.Col-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			indentation = append(indentation, '\t')
+		} else {
+			indentation = append(indentation, ' ')
+		}
+	}
+
+	return result + "\n\t" + string(line) + "\n\t" + string(indentation) + "^"
+}
@@ -0,0 +1,44 @@
+package errors
+
+import (
+	"bananascript/src/token"
+	"github.com/gookit/color"
+	"gotest.tools/assert"
+	"strings"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	file := "test.banana"
+	var err error = New(3, 7, &file, "Unexpected %s", "'}'")
+	assert.Equal(t, err.Error(), "test.banana:3:7: Unexpected '}'")
+
+	theToken := &token.Token{Type: token.RBrace, Line: 2, Col: 4}
+	parserError := NewFromToken(theToken, "Unexpected %s", "'}'")
+	assert.Equal(t, parserError.Error(), "2:4: Unexpected '}'")
+	assert.Equal(t, parserError.Token, theToken)
+	assert.Assert(t, New(1, 1, nil, "a").Token == nil)
+}
+
+func TestFormatError(t *testing.T) {
+	color.Disable()
+
+	source := "let a := 1;\n\tlet b := a / 0;\nlet c := b;"
+	err := New(2, 13, nil, "Division by zero")
+	assert.Equal(t, FormatError(source, err), "Error: Division by zero\n\tin 2:13\n\t\tlet b := a / 0;\n\t\t           ^")
+
+	err = New(1, 1, nil, "Unexpected token")
+	assert.Equal(t, FormatError(source, err), "Error: Unexpected token\n\tin 1:1\n\tlet a := 1;\n\t^")
+
+	for _, lineEnding := range []string{"\r\n", "\r"} {
+		err = New(2, 13, nil, "Division by zero")
+		assert.Equal(t, FormatError(strings.ReplaceAll(source, "\n", lineEnding), err),
+			"Error: Division by zero\n\tin 2:13\n\t\tlet b := a / 0;\n\t\t           ^")
+	}
+
+	err = New(5, 1, nil, "Unexpected EOF")
+	assert.Equal(t, FormatError(source, err), "Error: Unexpected EOF\n\tin 5:1")
+
+	err = New(1, 5, nil, "Unused variable 'a'")
+	assert.Equal(t, FormatWarning(source, err), "Warning: Unused variable 'a'\n\tin 1:5\n\tlet a := 1;\n\t    ^")
+}
@@ -3,6 +3,7 @@ package evaluator
 import (
 	"bananascript/src/types"
 	"reflect"
+	"sort"
 )
 
 type Environment struct {
@@ -10,14 +11,44 @@ type Environment struct {
 	parent           *Environment
 	store            map[string]Object
 	typeEnvironments map[types.Type]*Environment
+	evaluation       *evaluation
+}
+
+// nesting state of one evaluation, shared by an environment and everything extending it
+type evaluation struct {
+	depth    int
+	maxDepth int
+}
+
+type Snapshot struct {
+	scopes []*scopeSnapshot
+}
+
+type scopeSnapshot struct {
+	environment      *Environment
+	store            map[string]Object
+	typeEnvironments map[types.Type]*Environment
+	context          *types.Context
 }
 
 func NewEnvironment(context *types.Context) *Environment {
-	return &Environment{context: context, store: make(map[string]Object), typeEnvironments: make(map[types.Type]*Environment)}
+	return &Environment{context: context, evaluation: &evaluation{maxDepth: DefaultMaxDepth}}
 }
 
 func ExtendEnvironment(parent *Environment, context *types.Context) *Environment {
-	return &Environment{context: context, parent: parent, store: make(map[string]Object), typeEnvironments: make(map[types.Type]*Environment)}
+	return &Environment{context: context, parent: parent, evaluation: parent.getEvaluation()}
+}
+
+// limits how deeply evaluations in this environment and all environments extending it can nest
+func (environment *Environment) SetMaxDepth(maxDepth int) {
+	environment.getEvaluation().maxDepth = maxDepth
+}
+
+func (environment *Environment) getEvaluation() *evaluation {
+	if environment.evaluation == nil {
+		environment.evaluation = &evaluation{maxDepth: DefaultMaxDepth}
+	}
+	return environment.evaluation
 }
 
 func (environment *Environment) GetObjectStrict(name string) (Object, bool) {
@@ -33,6 +64,52 @@ func (environment *Environment) GetObject(name string) (Object, bool) {
 	return object, ok
 }
 
+// resolves an object that the type checker found the given number of scopes up, without searching other scopes,
+// so environments that do not line up with the contexts fail instead of finding another binding of the same name
+func (environment *Environment) GetObjectAtDepth(name string, depth int) (Object, bool) {
+	if owner := environment.ancestor(depth); owner != nil {
+		return owner.GetObjectStrict(name)
+	}
+	return nil, false
+}
+
+func (environment *Environment) ancestor(depth int) *Environment {
+	owner := environment
+	for i := 0; i < depth && owner != nil; i++ {
+		owner = owner.parent
+	}
+	return owner
+}
+
+// lists the names of all objects visible from this environment, sorted and without duplicates
+func (environment *Environment) DefinedNames() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for current := environment; current != nil; current = current.parent {
+		for name := range current.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolves the static type of a binding from the contexts, without evaluating anything
+func (environment *Environment) GetDefinedType(name string) (types.Type, bool) {
+	for current := environment; current != nil; current = current.parent {
+		if current.context == nil {
+			continue
+		}
+		if definedType, ok := current.context.GetMemberType(name); ok {
+			return definedType, true
+		}
+	}
+	return nil, false
+}
+
 func (environment *Environment) GetTypeMember(object Object, parentType types.Type, name string) (Object, bool) {
 	for theType, typeStore := range environment.typeEnvironments {
 		if theType.IsAssignable(parentType, environment.context) {
@@ -49,6 +126,12 @@ func (environment *Environment) GetTypeMember(object Object, parentType types.Ty
 }
 
 func (environment *Environment) DefineObject(name string, value Object) (Object, bool) {
+	if name == types.Discard {
+		return value, true
+	}
+	if environment.store == nil {
+		environment.store = make(map[string]Object)
+	}
 	environment.store[name] = value
 	return value, true
 }
@@ -62,10 +145,24 @@ func (environment *Environment) DefineTypeMember(parentType types.Type, name str
 	typeContext := types.GetMemberTypeContext(environment.context, parentType)
 	typeEnvironment := NewEnvironment(typeContext)
 	typeEnvironment.DefineObject(name, member)
+	if environment.typeEnvironments == nil {
+		environment.typeEnvironments = make(map[types.Type]*Environment)
+	}
 	environment.typeEnvironments[parentType] = typeEnvironment
 	return member, true
 }
 
+func (environment *Environment) AssignObjectAtDepth(name string, depth int, value Object) (Object, bool) {
+	owner := environment.ancestor(depth)
+	if owner == nil {
+		return nil, false
+	}
+	if _, exists := owner.GetObjectStrict(name); !exists {
+		return nil, false
+	}
+	return owner.DefineObject(name, value)
+}
+
 func (environment *Environment) AssignObject(name string, value Object) (Object, bool) {
 	if _, exists := environment.GetObjectStrict(name); exists {
 		return environment.DefineObject(name, value)
@@ -74,3 +171,57 @@ func (environment *Environment) AssignObject(name string, value Object) (Object,
 	}
 	return nil, false
 }
+
+// captures the bindings of this environment and all of its parents, so that anything defined or assigned
+// afterwards can be reverted with Restore
+func (environment *Environment) Snapshot() *Snapshot {
+	snapshot := &Snapshot{}
+	for current := environment; current != nil; current = current.parent {
+		scope := &scopeSnapshot{
+			environment:      current,
+			store:            cloneStore(current.store),
+			typeEnvironments: cloneTypeEnvironments(current.typeEnvironments),
+		}
+		if current.context != nil {
+			scope.context = current.context.Snapshot()
+		}
+		snapshot.scopes = append(snapshot.scopes, scope)
+	}
+	return snapshot
+}
+
+func (environment *Environment) Restore(snapshot *Snapshot) bool {
+	if len(snapshot.scopes) == 0 || snapshot.scopes[0].environment != environment {
+		return false
+	}
+	for _, scope := range snapshot.scopes {
+		scope.environment.store = cloneStore(scope.store)
+		scope.environment.typeEnvironments = cloneTypeEnvironments(scope.typeEnvironments)
+		if scope.context != nil {
+			scope.environment.context.Restore(scope.context)
+		}
+	}
+	return true
+}
+
+func cloneStore(store map[string]Object) map[string]Object {
+	if store == nil {
+		return nil
+	}
+	cloned := make(map[string]Object, len(store))
+	for name, object := range store {
+		cloned[name] = object
+	}
+	return cloned
+}
+
+func cloneTypeEnvironments(typeEnvironments map[types.Type]*Environment) map[types.Type]*Environment {
+	if typeEnvironments == nil {
+		return nil
+	}
+	cloned := make(map[types.Type]*Environment, len(typeEnvironments))
+	for parentType, typeEnvironment := range typeEnvironments {
+		cloned[parentType] = &Environment{context: typeEnvironment.context, store: cloneStore(typeEnvironment.store)}
+	}
+	return cloned
+}
@@ -4,19 +4,37 @@ import (
 	"bananascript/src/parser"
 	"bananascript/src/token"
 	"fmt"
-	"reflect"
+	"math"
+	"strings"
 )
 
+// deeper evaluation, like runaway recursion, fails with an error instead of overflowing the stack
+const DefaultMaxDepth = 20000
+
 func Eval(node parser.Node, environment *Environment) Object {
+	state := environment.getEvaluation()
+	if state.depth >= state.maxDepth {
+		return NewError("Maximum nesting depth exceeded")
+	}
+	state.depth++
+	defer func() {
+		state.depth--
+	}()
+	return evalNode(node, environment)
+}
+
+func evalNode(node parser.Node, environment *Environment) Object {
 	switch node := node.(type) {
 	case *parser.Program:
 		return evalProgram(node, environment)
 	case *parser.ExpressionStatement:
 		return Eval(node.Expression, environment)
+	case *parser.EmptyStatement:
+		return nil
 	case *parser.StringLiteral:
 		return &StringObject{Value: node.Value}
 	case *parser.IntegerLiteral:
-		return &IntegerObject{Value: node.Value}
+		return NewInteger(node.Value)
 	case *parser.FloatLiteral:
 		return &FloatObject{Value: node.Value}
 	case *parser.BooleanLiteral:
@@ -28,25 +46,40 @@ func Eval(node parser.Node, environment *Environment) Object {
 	case *parser.Identifier:
 		return evalIdentifierExpression(node, environment)
 	case *parser.InfixExpression:
-		return evalInfixExpression(node, environment)
+		return withToken(evalInfixExpression(node, environment), node.OperatorToken)
 	case *parser.PrefixExpression:
-		return evalPrefixExpression(node, environment)
+		return withToken(evalPrefixExpression(node, environment), node.PrefixToken)
 	case *parser.CallExpression:
 		return evalCallExpression(node, environment)
 	case *parser.AssignmentExpression:
 		return evalAssignmentExpression(node, environment)
 	case *parser.LetStatement:
 		return evalLetStatement(node, environment)
+	case *parser.MultiLetStatement:
+		for _, declaration := range node.Declarations {
+			if object := evalLetStatement(declaration, environment); isError(object) {
+				return object
+			}
+		}
+		return nil
 	case *parser.FunctionDefinitionStatement:
 		return evalFunctionDefinitionStatement(node, environment)
 	case *parser.ReturnStatement:
 		return evalReturnStatement(node, environment)
 	case *parser.BlockStatement:
 		return evalBlockStatement(node, environment)
+	case *parser.BlockExpression:
+		return evalBlockExpression(node, environment)
+	case *parser.IfExpression:
+		return evalIfExpression(node, environment)
 	case *parser.IfStatement:
 		return evalIfStatement(node, environment)
 	case *parser.WhileStatement:
 		return evalWhileStatement(node, environment)
+	case *parser.BreakStatement:
+		return &BreakObject{}
+	case *parser.ContinueStatement:
+		return &ContinueObject{}
 	case *parser.IncrementExpression:
 		return evalIncrementExpression(node, environment)
 	case *parser.MemberAccessExpression:
@@ -59,14 +92,33 @@ func Eval(node parser.Node, environment *Environment) Object {
 
 func evalProgram(program *parser.Program, environment *Environment) Object {
 	newEnvironment := ExtendEnvironment(environment, program.Context)
-	for _, statement := range program.Statements {
-		result := Eval(statement, newEnvironment)
-		switch result := result.(type) {
-		case *ErrorObject:
+	result := EvalStatements(program.Statements, newEnvironment)
+	switch result := result.(type) {
+	case *ErrorObject, *ExitObject:
+		return result
+	}
+	return nil
+}
+
+// evaluates top-level statements, defining all functions first so that they can be called before their definition
+func EvalStatements(statements []parser.Statement, environment *Environment) Object {
+	for _, statement := range statements {
+		if funcStatement, isFunc := statement.(*parser.FunctionDefinitionStatement); isFunc {
+			evalFunctionDefinitionStatement(funcStatement, environment)
+		}
+	}
+
+	var result Object
+	for _, statement := range statements {
+		if _, isFunc := statement.(*parser.FunctionDefinitionStatement); isFunc {
+			continue
+		}
+		result = Eval(statement, environment)
+		if isError(result) {
 			return result
 		}
 	}
-	return nil
+	return result
 }
 
 func evalPrefixExpression(prefixExpression *parser.PrefixExpression, environment *Environment) Object {
@@ -82,10 +134,17 @@ func evalPrefixExpression(prefixExpression *parser.PrefixExpression, environment
 	case token.Minus:
 		switch object := object.(type) {
 		case *IntegerObject:
-			return &IntegerObject{Value: -object.Value}
+			return NewInteger(-object.Value)
 		case *FloatObject:
 			return &FloatObject{Value: -object.Value}
 		}
+	case token.Plus:
+		switch object := object.(type) {
+		case *IntegerObject:
+			return NewInteger(object.Value)
+		case *FloatObject:
+			return &FloatObject{Value: object.Value}
+		}
 	}
 
 	return NewError("Unknown prefix operator")
@@ -111,6 +170,10 @@ func evalInfixExpression(infixExpression *parser.InfixExpression, environment *E
 		return &BooleanObject{Value: implicitBoolConversion(rightObject)}
 	}
 
+	if methodName, ok := parser.OperatorMethods[infixExpression.Operator]; ok && !hasBuiltinOperator(infixExpression.Operator, leftObject, rightObject) {
+		return evalOperatorMethod(methodName, leftObject, rightObject, environment)
+	}
+
 	switch infixExpression.Operator {
 	case token.EQ:
 		return &BooleanObject{Value: evalEquals(leftObject, rightObject)}
@@ -140,6 +203,13 @@ func evalInfixExpression(infixExpression *parser.InfixExpression, environment *E
 			func(left int64, right int64) Object { return &BooleanObject{Value: left >= right} },
 			func(left float64, right float64) Object { return &BooleanObject{Value: left >= right} },
 		)
+	case token.In:
+		left, leftIsString := leftObject.(*StringObject)
+		right, rightIsString := rightObject.(*StringObject)
+		if leftIsString && rightIsString {
+			return &BooleanObject{Value: strings.Contains(right.Value, left.Value)}
+		}
+		return NewError("Unknown infix operator")
 	case token.Plus:
 		_, leftIsString := leftObject.(*StringObject)
 		_, rightIsString := rightObject.(*StringObject)
@@ -148,34 +218,120 @@ func evalInfixExpression(infixExpression *parser.InfixExpression, environment *E
 		}
 		return evalNumericInfix(
 			leftObject, rightObject,
-			func(left int64, right int64) Object { return &IntegerObject{Value: left + right} },
+			func(left int64, right int64) Object { return NewInteger(left + right) },
 			func(left float64, right float64) Object { return &FloatObject{Value: left + right} },
 		)
 	case token.Minus:
 		return evalNumericInfix(
 			leftObject, rightObject,
-			func(left int64, right int64) Object { return &IntegerObject{Value: left - right} },
+			func(left int64, right int64) Object { return NewInteger(left - right) },
 			func(left float64, right float64) Object { return &FloatObject{Value: left - right} },
 		)
 	case token.Slash:
 		return evalNumericInfix(
 			leftObject, rightObject,
-			func(left int64, right int64) Object { return &IntegerObject{Value: left / right} },
+			func(left int64, right int64) Object {
+				if right == 0 {
+					return NewError("Division by zero")
+				}
+				return NewInteger(left / right)
+			},
 			func(left float64, right float64) Object { return &FloatObject{Value: left / right} },
 		)
 	case token.Star:
 		return evalNumericInfix(
 			leftObject, rightObject,
-			func(left int64, right int64) Object { return &IntegerObject{Value: left * right} },
+			func(left int64, right int64) Object { return NewInteger(left * right) },
 			func(left float64, right float64) Object { return &FloatObject{Value: left * right} },
 		)
+	case token.Percent:
+		return evalNumericInfix(
+			leftObject, rightObject,
+			func(left int64, right int64) Object {
+				if right == 0 {
+					return NewError("Division by zero")
+				}
+				return NewInteger(left % right)
+			},
+			func(left float64, right float64) Object { return &FloatObject{Value: math.Mod(left, right)} },
+		)
+	case token.Power:
+		return evalNumericInfix(
+			leftObject, rightObject,
+			func(left int64, right int64) Object {
+				if right < 0 {
+					return NewError("Negative exponent for int (%d)", right)
+				}
+				return NewInteger(integerPower(left, right))
+			},
+			func(left float64, right float64) Object {
+				result := math.Pow(left, right)
+				if isFinite(left) && isFinite(right) && !isFinite(result) {
+					return NewError("Power of %s and %s has no finite real result",
+						(&FloatObject{Value: left}).ToString(), (&FloatObject{Value: right}).ToString())
+				}
+				return &FloatObject{Value: result}
+			},
+		)
 	default:
 		return NewError("Unknown infix operator")
 	}
 }
 
+func integerPower(base int64, exponent int64) int64 {
+	result := int64(1)
+	for exponent > 0 {
+		if exponent%2 == 1 {
+			result *= base
+		}
+		base *= base
+		exponent /= 2
+	}
+	return result
+}
+
+// like the math builtins, ** fails for finite operands without a finite result, NaN and Infinity pass through
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+func hasBuiltinOperator(operator token.Type, left Object, right Object) bool {
+	_, leftIsString := left.(*StringObject)
+	_, rightIsString := right.(*StringObject)
+	if operator == token.Plus && (leftIsString || rightIsString) {
+		return true
+	}
+	return isNumeric(left) && isNumeric(right)
+}
+
+func isNumeric(object Object) bool {
+	switch object.(type) {
+	case *IntegerObject, *FloatObject:
+		return true
+	default:
+		return false
+	}
+}
+
+func evalOperatorMethod(methodName string, left Object, right Object, environment *Environment) Object {
+	member, ok := environment.GetTypeMember(left, left.Type(), methodName)
+	if !ok {
+		return NewError("Invalid infix operator")
+	}
+	function, isFunction := member.(Function)
+	if !isFunction {
+		return NewError("Invalid infix operator")
+	}
+	switch returned := function.With(left).Execute([]Object{right}).(type) {
+	case *ReturnObject:
+		return returned.Object
+	default:
+		return returned
+	}
+}
+
 func evalEquals(left Object, right Object) bool {
-	return reflect.DeepEqual(left, right)
+	return ObjectsEqual(left, right)
 }
 
 func evalNumericInfix(left Object, right Object, intConstructor func(left int64, right int64) Object, floatConstructor func(left float64, right float64) Object) Object {
@@ -205,28 +361,39 @@ func evalAssignmentExpression(assignmentExpression *parser.AssignmentExpression,
 		return object
 	}
 
-	name := assignmentExpression.Name.Value
-	if object, ok := environment.AssignObject(name, object); ok {
+	if object, ok := assignIdentifier(assignmentExpression.Name, object, environment); ok {
 		return object
 	} else {
-		return NewError("Cannot resolve variable")
+		return unresolvedError(assignmentExpression.Name)
 	}
 }
 
 func evalCallExpression(callExpression *parser.CallExpression, environment *Environment) Object {
 	function := Eval(callExpression.Function, environment)
 	switch function := function.(type) {
-	case *ErrorObject:
+	case *ErrorObject, *ExitObject:
 		return function
 	case Function:
 		argumentObjects := make([]Object, 0)
+		// arguments are evaluated from left to right
 		for _, argument := range callExpression.Arguments {
-			argumentObjects = append(argumentObjects, Eval(argument, environment))
+			argumentObject := Eval(argument, environment)
+			if isError(argumentObject) {
+				return argumentObject
+			}
+			argumentObjects = append(argumentObjects, argumentObject)
+		}
+		var returned Object
+		if scopedFunction, isScoped := function.(ScopedFunction); isScoped {
+			returned = scopedFunction.ExecuteInScope(environment, argumentObjects)
+		} else {
+			returned = function.Execute(argumentObjects)
 		}
-		returned := function.Execute(argumentObjects)
 		switch returned := returned.(type) {
 		case *ReturnObject:
 			return returned.Object
+		case *ErrorObject:
+			return withToken(returned, callExpression.Function.Token())
 		default:
 			return returned
 		}
@@ -236,10 +403,10 @@ func evalCallExpression(callExpression *parser.CallExpression, environment *Envi
 }
 
 func evalIdentifierExpression(identifier *parser.Identifier, environment *Environment) Object {
-	if object, exists := environment.GetObject(identifier.Value); exists {
+	if object, exists := resolveIdentifier(identifier, environment); exists {
 		return object
 	} else {
-		return NewError("Cannot resolve identifier")
+		return unresolvedError(identifier)
 	}
 }
 
@@ -271,6 +438,9 @@ func evalFunctionDefinitionStatement(funcStatement *parser.FunctionDefinitionSta
 		Context:      funcStatement.FunctionContext,
 		FunctionType: funcStatement.FunctionType,
 	}
+	if funcStatement.ThisName != nil {
+		object.ThisName = funcStatement.ThisName.Value
+	}
 
 	if funcStatement.ThisType != nil {
 		environment.DefineTypeMember(funcStatement.ThisType, name, object)
@@ -295,8 +465,8 @@ func evalBlockStatement(blockStatement *parser.BlockStatement, environment *Envi
 		object := Eval(statement, newEnvironment)
 		if object != nil {
 			switch object := object.(type) {
-			case *ErrorObject, *ReturnObject:
-				return object
+			case *ErrorObject, *ExitObject, *ReturnObject, *BreakObject, *ContinueObject:
+				return object // passed up until a function or loop handles it
 			default:
 				continue
 			}
@@ -306,6 +476,38 @@ func evalBlockStatement(blockStatement *parser.BlockStatement, environment *Envi
 	return nil
 }
 
+func evalBlockExpression(blockExpression *parser.BlockExpression, environment *Environment) Object {
+	block := blockExpression.Block
+	newEnvironment := ExtendEnvironment(ExtendEnvironment(environment, blockExpression.Context), block.Context)
+
+	var object Object
+	for _, statement := range block.Statements {
+		object = Eval(statement, newEnvironment)
+		if isError(object) {
+			return object
+		}
+	}
+
+	if len(block.Statements) == 0 {
+		return nil
+	}
+	if expressionStatement, isExpression := block.Statements[len(block.Statements)-1].(*parser.ExpressionStatement); !isExpression || expressionStatement.SemiToken != nil {
+		return nil
+	}
+	return object
+}
+
+func evalIfExpression(ifExpression *parser.IfExpression, environment *Environment) Object {
+	condition := Eval(ifExpression.Condition, environment)
+	if isError(condition) {
+		return condition
+	}
+	if implicitBoolConversion(condition) {
+		return Eval(ifExpression.Consequence, environment)
+	}
+	return Eval(ifExpression.Alternative, environment)
+}
+
 func evalIfStatement(ifStatement *parser.IfStatement, environment *Environment) Object {
 	condition := Eval(ifStatement.Condition, environment)
 	if isError(condition) {
@@ -318,7 +520,7 @@ func evalIfStatement(ifStatement *parser.IfStatement, environment *Environment)
 		object = Eval(ifStatement.Alternative, ExtendEnvironment(environment, ifStatement.AlternativeContext))
 	}
 	switch object.(type) {
-	case *ErrorObject, *ReturnObject:
+	case *ErrorObject, *ExitObject, *ReturnObject, *BreakObject, *ContinueObject:
 		return object
 	default:
 		return nil
@@ -326,59 +528,71 @@ func evalIfStatement(ifStatement *parser.IfStatement, environment *Environment)
 }
 
 func evalWhileStatement(whileStatement *parser.WhileStatement, environment *Environment) Object {
-	for {
+	for iterations := 0; ; iterations++ {
 		condition := Eval(whileStatement.Condition, environment)
 		if isError(condition) {
 			return condition
 		}
 		if !implicitBoolConversion(condition) {
+			if iterations == 0 && whileStatement.Alternative != nil {
+				return evalWhileAlternative(whileStatement, environment)
+			}
 			return nil
 		}
+		// every iteration gets a fresh scope, only variables declared outside of the loop keep their values
 		object := Eval(whileStatement.Statement, ExtendEnvironment(environment, whileStatement.StatementContext))
 		switch object := object.(type) {
-		case *ErrorObject, *ReturnObject:
+		case *ErrorObject, *ExitObject, *ReturnObject:
 			return object
+		case *BreakObject:
+			return nil
 		default:
 			continue
 		}
 	}
 }
 
+// the 'else' branch of a loop that never ran, it is evaluated like the 'else' of an if statement
+func evalWhileAlternative(whileStatement *parser.WhileStatement, environment *Environment) Object {
+	object := Eval(whileStatement.Alternative, ExtendEnvironment(environment, whileStatement.AlternativeContext))
+	switch object.(type) {
+	case *ErrorObject, *ExitObject, *ReturnObject, *BreakObject, *ContinueObject:
+		return object
+	default:
+		return nil
+	}
+}
+
 func evalIncrementExpression(incrementExpression *parser.IncrementExpression, environment *Environment) Object {
 
-	object, exists := environment.GetObject(incrementExpression.Name.Value)
+	object, exists := resolveIdentifier(incrementExpression.Name, environment)
 	if !exists {
-		return NewError("Cannot resolve identifier")
+		return unresolvedError(incrementExpression.Name)
 	}
 
+	delta := int64(1)
+	if incrementExpression.Operator == token.Decrement {
+		delta = -1
+	}
+
+	// numbers are never mutated in place, the variable is rebound to a new object instead
+	var newObject Object
 	switch object := object.(type) {
 	case *IntegerObject:
-		oldValue := object.Value
-		if incrementExpression.Operator == token.Increment {
-			object.Value++
-		} else {
-			object.Value--
-		}
-		if incrementExpression.Pre {
-			return object
-		} else {
-			return &IntegerObject{Value: oldValue}
-		}
+		newObject = NewInteger(object.Value + delta)
 	case *FloatObject:
-		oldValue := object.Value
-		if incrementExpression.Operator == token.Increment {
-			object.Value++
-		} else {
-			object.Value--
-		}
-		if incrementExpression.Pre {
-			return object
-		} else {
-			return &FloatObject{Value: oldValue}
-		}
+		newObject = &FloatObject{Value: object.Value + float64(delta)}
+	default:
+		return NewError("Cannot increment non-int")
 	}
 
-	return NewError("Cannot increment non-int")
+	if _, ok := assignIdentifier(incrementExpression.Name, newObject, environment); !ok {
+		return unresolvedError(incrementExpression.Name)
+	}
+	if incrementExpression.Pre {
+		return newObject
+	}
+	return object
 }
 
 func evalMemberAccessExpression(memberAccessExpression *parser.MemberAccessExpression, environment *Environment) Object {
@@ -401,6 +615,27 @@ func evalMemberAccessExpression(memberAccessExpression *parser.MemberAccessExpre
 	}
 }
 
+func resolveIdentifier(identifier *parser.Identifier, environment *Environment) (Object, bool) {
+	if depth, resolved := identifier.Depth(); resolved {
+		return environment.GetObjectAtDepth(identifier.Value, depth)
+	}
+	return environment.GetObject(identifier.Value)
+}
+
+// the identifier is not bound yet, e.g. a variable that is declared after the code that runs
+func unresolvedError(identifier *parser.Identifier) *ErrorObject {
+	err := NewError("Cannot resolve identifier '%s'", identifier.Value)
+	err.Token = identifier.IdentToken
+	return err
+}
+
+func assignIdentifier(identifier *parser.Identifier, value Object, environment *Environment) (Object, bool) {
+	if depth, resolved := identifier.Depth(); resolved {
+		return environment.AssignObjectAtDepth(identifier.Value, depth, value)
+	}
+	return environment.AssignObject(identifier.Value, value)
+}
+
 func implicitBoolConversion(object Object) bool {
 	switch object := object.(type) {
 	case *BooleanObject:
@@ -420,7 +655,20 @@ func NewError(format string, args ...interface{}) *ErrorObject {
 	return &ErrorObject{Message: fmt.Sprintf(format, args...)}
 }
 
+// locates an error at the token if it does not know where it happened yet, errors of inner expressions keep theirs
+func withToken(object Object, token *token.Token) Object {
+	if err, isError := object.(*ErrorObject); isError && err.Token == nil {
+		err.Token = token
+	}
+	return object
+}
+
+// exit unwinds the same way as an error
 func isError(object Object) bool {
-	_, isError := object.(*ErrorObject)
-	return isError
+	switch object.(type) {
+	case *ErrorObject, *ExitObject:
+		return true
+	default:
+		return false
+	}
 }
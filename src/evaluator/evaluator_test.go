@@ -3,8 +3,12 @@ package evaluator
 import (
 	"bananascript/src/lexer"
 	"bananascript/src/parser"
+	"bananascript/src/token"
 	"bananascript/src/types"
+	"fmt"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/assert"
+	"strings"
 	"testing"
 )
 
@@ -34,6 +38,415 @@ func TestEvaluator(t *testing.T) {
 		"1 + 2 * 3 - 4;",
 		&IntegerObject{Value: 3},
 	)
+
+	assertObject(t,
+		"+5;",
+		&IntegerObject{Value: 5},
+	)
+
+	assertObject(t,
+		"+3.2;",
+		&FloatObject{Value: 3.2},
+	)
+
+	assertObject(t,
+		"2 + +-3;",
+		&IntegerObject{Value: -1},
+	)
+}
+
+func TestModuloAndPower(t *testing.T) {
+	assertObject(t, "7 % 3;", &IntegerObject{Value: 1})
+	assertObject(t, "-7 % 3;", &IntegerObject{Value: -1})
+	assertObject(t, "7.5 % 2;", &FloatObject{Value: 1.5})
+	assertObject(t, "2 ** 10;", &IntegerObject{Value: 1024})
+	assertObject(t, "2 ** 3 ** 2;", &IntegerObject{Value: 512})
+	assertObject(t, "-2 ** 2;", &IntegerObject{Value: -4})
+	assertObject(t, "4 ** 0.5;", &FloatObject{Value: 2})
+	assertObject(t, "2 * 3 ** 2;", &IntegerObject{Value: 18})
+
+	_, isError := evalCode(t, "5 % 0;", false).(*ErrorObject)
+	assert.Assert(t, isError)
+	_, isError = evalCode(t, "2 ** -1;", false).(*ErrorObject)
+	assert.Assert(t, isError)
+
+	// like the math builtins, finite operands must have a finite result, NaN and Infinity pass through
+	assert.Equal(t, evalCode(t, "(-8.0) ** (1.0 / 2.0);", false).(*ErrorObject).Message, "Power of -8 and 0.5 has no finite real result")
+	assert.Equal(t, evalCode(t, "0.0 ** -1.0;", false).(*ErrorObject).Message, "Power of 0 and -1 has no finite real result")
+	assert.Equal(t, evalCode(t, "10 ** 400.0;", false).(*ErrorObject).Message, "Power of 10 and 400 has no finite real result")
+	assertObject(t, "(1.0 / 0.0) ** 2.0 == 1.0 / 0.0;", &BooleanObject{Value: true})
+	assertObject(t, "(1.0 / 0.0) ** -1.0;", &FloatObject{Value: 0})
+
+	environment := evalStatements(t, "let a := 10; a %= 4; let b := 3; b **= 3; let c := 2.0; c **= 3; c %= 5;")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 27})
+	assertVariable(t, environment, "c", &FloatObject{Value: 3})
+
+	environment = evalStatements(t, "let a := 1; a += 4; a -= 1; a *= 6; a /= 4;")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 6})
+}
+
+func TestMultiLet(t *testing.T) {
+	environment := evalStatements(t, "let a := 1, b := a + 1, c: string? = \"c\"; let d := 4")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "c", &StringObject{Value: "c"})
+	assertVariable(t, environment, "d", &IntegerObject{Value: 4})
+}
+
+func TestIntegerCache(t *testing.T) {
+	assert.Assert(t, NewInteger(5) == NewInteger(5))
+	assert.Assert(t, NewInteger(-128) == NewInteger(-128))
+	assert.Assert(t, NewInteger(1000) != NewInteger(1000))
+
+	environment := evalStatements(t, "let a := 5; a++; ++a; let b := 7; b--; let c := 300; c++;")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 7})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 6})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 301})
+	assert.Equal(t, NewInteger(5).Value, int64(5))
+	assert.Equal(t, NewInteger(7).Value, int64(7))
+
+	environment = evalStatements(t, "let a := 5; a++; let five := 5; let b := 2; let c := b++ + b;")
+	assertVariable(t, environment, "five", &IntegerObject{Value: 5})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 5})
+}
+
+func TestIncrementAliasing(t *testing.T) {
+	environment := evalStatements(t, "let a := 1; let b := a; a++;")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 1})
+
+	environment = evalStatements(t, "let a := 1000; let b := 0; b = a; --b; let c := 1.5; let d := c; c++;")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 1000})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 999})
+	assertVariable(t, environment, "c", &FloatObject{Value: 2.5})
+	assertVariable(t, environment, "d", &FloatObject{Value: 1.5})
+
+	environment = evalStatements(t, "let a := 1; fn inc(x: int) int { x++; return x; } let b := inc(a);")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 2})
+}
+
+func TestNestedFunctions(t *testing.T) {
+	environment := evalStatements(t, `
+		fn outer(x: int) int {
+			let base := 10;
+			fn helper(y: int) int { return base + x + y; }
+			base = 20;
+			return helper(1) + helper(2);
+		}
+		fn factorial(n: int) int {
+			fn step(k: int) int { if k <= 1 { return 1; } return k * step(k - 1); }
+			return step(n);
+		}
+		let a := outer(5);
+		let b := factorial(5);
+	`)
+	assertVariable(t, environment, "a", &IntegerObject{Value: 53})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 120})
+	_, ok := environment.GetObject("helper")
+	assert.Assert(t, !ok)
+}
+
+func TestMaximumDepth(t *testing.T) {
+	environment := evalStatements(t, "fn count(n: int) int { if n == 0 { return 0; } return 1 + count(n - 1); } let a := count(1000);")
+	assertVariable(t, environment, "a", &IntegerObject{Value: 1000})
+
+	context := types.NewContext()
+	program := parseCell(t, "fn forever(n: int) int { return forever(n + 1); } forever(0);", context)
+	foreverEnvironment := NewEnvironment(program.Context)
+	object := EvalStatements(program.Statements, foreverEnvironment)
+	assert.DeepEqual(t, object, &ErrorObject{Message: "Maximum nesting depth exceeded"},
+		cmpopts.IgnoreFields(ErrorObject{}, "Token"))
+	assert.Equal(t, foreverEnvironment.evaluation.depth, 0)
+
+	program = parseCell(t, "fn count(n: int) int { if n == 0 { return 0; } return 1 + count(n - 1); } count(100);", context)
+	limited := NewEnvironment(program.Context)
+	limited.SetMaxDepth(50)
+	object = EvalStatements(program.Statements, limited)
+	assert.DeepEqual(t, object, &ErrorObject{Message: "Maximum nesting depth exceeded"},
+		cmpopts.IgnoreFields(ErrorObject{}, "Token"))
+	assert.DeepEqual(t, EvalStatements(program.Statements, NewEnvironment(program.Context)), &IntegerObject{Value: 100})
+}
+
+func TestConcurrentEvaluation(t *testing.T) {
+	context := types.NewContext()
+	program := parseCell(t, "fn count(n: int) int { if n == 0 { return 0; } return 1 + count(n - 1); } count(1000);", context)
+
+	results := make(chan Object)
+	for i := 0; i < 8; i++ {
+		go func() {
+			results <- EvalStatements(program.Statements, NewEnvironment(program.Context))
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		assert.DeepEqual(t, <-results, &IntegerObject{Value: 1000})
+	}
+}
+
+func TestBoundMethods(t *testing.T) {
+	environment := evalStatements(t, `
+		fn (int)::plus(other: int) int { return this + other; }
+		fn (string)::wrap(left: string, right: string) string { return left + this + right; }
+		let n := 21;
+		let f: fn(int) int = n.plus;
+		n = 0;
+		let a := f(21);
+		let g := "x".wrap;
+		let b := g("<", ">");
+		let c := (3).plus;
+	`)
+	assertVariable(t, environment, "a", &IntegerObject{Value: 42})
+	assertVariable(t, environment, "b", &StringObject{Value: "<x>"})
+	c, _ := environment.GetObject("c")
+	assertVariable(t, environment, "n", &IntegerObject{Value: 0})
+	assert.DeepEqual(t, c.(Function).Execute([]Object{NewInteger(4)}), &ReturnObject{Object: NewInteger(7)})
+}
+
+func TestEscapedIdentifiers(t *testing.T) {
+	environment := evalStatements(t, `
+		let `+"`let`"+` := 1;
+		fn `+"`fn`"+`(`+"`if`"+`: int) int { return `+"`if`"+` * 2; }
+		let a := `+"`fn`"+`(`+"`let`"+` + 1);
+		`+"`while`"+` := "x";
+	`)
+	assertVariable(t, environment, "let", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "a", &IntegerObject{Value: 4})
+	assertVariable(t, environment, "while", &StringObject{Value: "x"})
+}
+
+func TestNamedReceiver(t *testing.T) {
+
+	environment := evalStatements(t, `
+		fn (self: int)::plus(other: int) int { return self + other; }
+		fn (s: string)::twice() string { return s + this; }
+		let a := (1).plus(2);
+		let b := "ab".twice();
+		let add := (10).plus;
+		let c := add(5);
+	`)
+
+	assertVariable(t, environment, "a", &IntegerObject{Value: 3})
+	assertVariable(t, environment, "b", &StringObject{Value: "abab"})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 15})
+}
+
+func TestChainedAssignment(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let a := 1;
+		let b := 2;
+		let c := 3;
+		a = b = 5;
+		c += a -= 2;
+	`)
+
+	assertVariable(t, environment, "a", &IntegerObject{Value: 3})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 5})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 6})
+}
+
+func TestArgumentCount(t *testing.T) {
+	environment := evalStatements(t, `
+		fn add(a: int, b: int) int { return a + b; }
+		fn (int)::plus(other: int) int { return this + other; }
+		let f := add;
+		let g := (1).plus;
+	`)
+	f, _ := environment.GetObject("f")
+	g, _ := environment.GetObject("g")
+	assert.DeepEqual(t, f.(Function).Execute([]Object{NewInteger(1)}), NewError("Expected 2 arguments, got 1"))
+	assert.DeepEqual(t, f.(Function).Execute([]Object{NewInteger(1), NewInteger(2), NewInteger(3)}),
+		NewError("Expected 2 arguments, got 3"))
+	assert.DeepEqual(t, f.(Function).Bind(NewInteger(1)).Execute([]Object{}), NewError("Expected 1 arguments, got 0"))
+	assert.DeepEqual(t, g.(Function).Execute([]Object{}), NewError("Expected 1 arguments, got 0"))
+	assert.DeepEqual(t, f.(Function).Execute([]Object{NewInteger(1), NewInteger(2)}), &ReturnObject{Object: NewInteger(3)})
+}
+
+func TestGoValue(t *testing.T) {
+	cases := map[string]interface{}{
+		"42;":            int64(42),
+		"-1.5;":          -1.5,
+		"\"a\" + \"b\";": "ab",
+		"1 < 2;":         true,
+		"null;":          nil,
+	}
+	for input, expected := range cases {
+		value, err := ToGoValue(evalCode(t, input, false))
+		assert.NilError(t, err, input)
+		assert.Equal(t, value, expected, input)
+	}
+
+	value, err := ToGoValue(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, value, nil)
+
+	_, err = ToGoValue(evalCode(t, "1 / 0;", false))
+	assert.Error(t, err, "1:3: Division by zero")
+
+	environment := evalStatements(t, "fn f() {}")
+	function, _ := environment.GetObject("f")
+	_, err = ToGoValue(function)
+	assert.Error(t, err, "Cannot convert '[Function]' to a Go value")
+}
+
+func TestErrorObject(t *testing.T) {
+	file := "test.banana"
+	var err error = &ErrorObject{Message: "Division by zero", Token: &token.Token{Line: 2, Col: 5, File: &file}}
+	assert.Equal(t, err.Error(), "test.banana:2:5: Division by zero")
+	assert.Equal(t, NewError("Division by zero").Error(), "Division by zero")
+}
+
+func TestRecursiveInterfaces(t *testing.T) {
+	environment := evalStatements(t, `
+		type Even := iface { flip: fn() Odd; };
+		type Odd := iface { flip: fn() Even; };
+		fn (int)::flip() float { return this * 2.0; }
+		fn (float)::flip() int { return 3; }
+		fn twice(e: Even) Even { return e.flip().flip(); }
+		let a := twice(1);
+		let b := (1).flip();
+	`)
+	assertVariable(t, environment, "a", &IntegerObject{Value: 3})
+	assertVariable(t, environment, "b", &FloatObject{Value: 2})
+}
+
+func TestEquals(t *testing.T) {
+
+	environment := evalStatements(t, `
+		fn a() {}
+		fn b() {}
+		fn (int)::double() int { return this * 2; }
+		fn makeCounter() fn() int {
+			let count := 0;
+			fn counter() int { return count++; }
+			return counter;
+		}
+		let f := a;
+
+		let intInt := 1 == 1;
+		let intIntDifferent := 1 == 2;
+		let intFloat := 2 == 2.0;
+		let floatInt := 2.5 == 2;
+		let floatFloat := 0.5 == 0.5;
+		let stringString := "a" == "a";
+		let stringStringDifferent := "a" == "b";
+		let stringInt := "1" == 1;
+		let boolBool := true == true;
+		let boolInt := true == 1;
+		let nullNull := null == null;
+		let nullInt := null == 0;
+		let functionSame := f == a;
+		let functionDifferent := a == b;
+		let methodSameReceiver := (1).double == (1).double;
+		let methodDifferentReceiver := (1).double == (2).double;
+		let closureDifferentScope := makeCounter() == makeCounter();
+		let voidVoid := a() == b();
+	`)
+
+	expected := map[string]bool{
+		"intInt":                  true,
+		"intIntDifferent":         false,
+		"intFloat":                true,
+		"floatInt":                false,
+		"floatFloat":              true,
+		"stringString":            true,
+		"stringStringDifferent":   false,
+		"stringInt":               false,
+		"boolBool":                true,
+		"boolInt":                 false,
+		"nullNull":                true,
+		"nullInt":                 false,
+		"functionSame":            true,
+		"functionDifferent":       false,
+		"methodSameReceiver":      true,
+		"methodDifferentReceiver": false,
+		"closureDifferentScope":   false,
+		"voidVoid":                true,
+	}
+	for name, value := range expected {
+		assertVariable(t, environment, name, &BooleanObject{Value: value})
+	}
+}
+
+func TestInOperator(t *testing.T) {
+	assertObject(t, "\"ell\" in \"hello\";", &BooleanObject{Value: true})
+	assertObject(t, "\"\" in \"hello\";", &BooleanObject{Value: true})
+	assertObject(t, "\"hello!\" in \"hello\";", &BooleanObject{Value: false})
+	assertObject(t, "\"L\" in \"hello\";", &BooleanObject{Value: false})
+	assertObject(t, "\"h\" + \"e\" in \"hello\";", &BooleanObject{Value: true})
+	assertObject(t, "!(\"x\" in \"hello\");", &BooleanObject{Value: true})
+}
+
+func TestDivision(t *testing.T) {
+	assertObject(t, "5 / 2;", NewInteger(2))
+	assertObject(t, "4 / 2;", NewInteger(2))
+	assertObject(t, "-5 / 2;", NewInteger(-2))
+	assertObject(t, "5.0 / 2;", &FloatObject{Value: 2.5})
+	assertObject(t, "5 / 2.0;", &FloatObject{Value: 2.5})
+	assertObject(t, "5 / 2 == 2;", &BooleanObject{Value: true})
+	assertObject(t, "5.0 / 2 == 2.5;", &BooleanObject{Value: true})
+	assertObject(t, "(5 / 2) * 2.0;", &FloatObject{Value: 4})
+}
+
+func TestShortCircuitErrors(t *testing.T) {
+	assertObject(t, "false && (1 / 0);", &BooleanObject{Value: false})
+	assertObject(t, "true || (1 / 0);", &BooleanObject{Value: true})
+	assertObject(t, "true && (1 / 0);", NewError("Division by zero"))
+	assertObject(t, "false || (1 / 0);", NewError("Division by zero"))
+	assertObject(t, "(1 / 0) && false;", NewError("Division by zero"))
+	assertObject(t, "(1 % 0) || true;", NewError("Division by zero"))
+	assertObject(t, "false && true || (1 / 0);", NewError("Division by zero"))
+	assertObject(t, "true || (1 / 0) && false;", &BooleanObject{Value: true})
+}
+
+func TestNumberLiterals(t *testing.T) {
+	assertObject(t, "1_000 + 1;", NewInteger(1001))
+	assertObject(t, "1_000.000_1;", &FloatObject{Value: 1000.0001})
+	assertObject(t, "1.5e3 + 2E-1;", &FloatObject{Value: 1500.2})
+	assertObject(t, "1_0e1_0 == 100_000_000_000.0;", &BooleanObject{Value: true})
+}
+
+func TestFoldedEvaluation(t *testing.T) {
+
+	inputs := []string{
+		"2 + 3 * 4;",
+		"-(1.5 * 2) + 7 / 2;",
+		"\"a\" + 1 + 2.5 + false;",
+		"!(1 < 2) || 3 == 3 && \"\";",
+		"1 / 0;",
+		"+2.5 - +1;",
+		"7 % 3 + 2.5 ** 2 % 4;",
+		"(-8.0) ** (1.0 / 3.0);",
+		"0.0 ** -1.0;",
+		"1 % 0;",
+		"false && (1 / 0);",
+		"true && (1 / 0);",
+	}
+
+	for _, input := range inputs {
+		expected := evalCode(t, input, false)
+		folded := evalCode(t, input, true)
+		assert.DeepEqual(t, folded, expected)
+	}
+}
+
+func evalCode(t *testing.T, input string, fold bool) Object {
+
+	theLexer := lexer.FromCode(input)
+	theParser := parser.New(theLexer)
+
+	context := types.NewContext()
+	environment := NewEnvironment(context)
+	program, errors := theParser.ParseProgram(context)
+	for _, err := range errors {
+		t.Error(err.Message)
+	}
+
+	if fold {
+		parser.FoldConstants(program)
+	}
+	return Eval(program.Statements[0], ExtendEnvironment(environment, program.Context))
 }
 
 func assertObject(t *testing.T, input string, expected Object) {
@@ -50,6 +463,635 @@ func assertObject(t *testing.T, input string, expected Object) {
 			t.Error(err.Message)
 		}
 	} else {
-		assert.DeepEqual(t, Eval(program.Statements[0], environment), expected)
+		assert.DeepEqual(t, Eval(program.Statements[0], ExtendEnvironment(environment, program.Context)), expected,
+			cmpopts.IgnoreFields(ErrorObject{}, "Token"))
+	}
+}
+
+func TestLookupAtDepth(t *testing.T) {
+	outer := NewEnvironment(types.NewContext())
+	outer.DefineObject("x", &IntegerObject{Value: 1})
+	inner := ExtendEnvironment(outer, types.NewContext())
+
+	object, ok := inner.GetObjectAtDepth("x", 1)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, object, &IntegerObject{Value: 1})
+
+	// scopes that do not line up with the type checker's are not papered over by a search of the parents
+	_, ok = inner.GetObjectAtDepth("x", 0)
+	assert.Assert(t, !ok)
+	_, ok = inner.GetObjectAtDepth("x", 2)
+	assert.Assert(t, !ok)
+	_, ok = inner.AssignObjectAtDepth("x", 0, &IntegerObject{Value: 2})
+	assert.Assert(t, !ok)
+	assertVariable(t, outer, "x", &IntegerObject{Value: 1})
+
+	_, ok = inner.AssignObjectAtDepth("x", 1, &IntegerObject{Value: 3})
+	assert.Assert(t, ok)
+	assertVariable(t, outer, "x", &IntegerObject{Value: 3})
+}
+
+func TestShadowing(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let x := 1;
+		let a := 0;
+		let b := 0;
+		let c := 0;
+		{
+			let x := 2;
+			a = x;
+			{
+				x = 3;
+				b = x;
+				let x := 4;
+				x++;
+			}
+		}
+		c = x;
+	`)
+
+	assertVariable(t, environment, "a", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 3})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "x", &IntegerObject{Value: 1})
+
+	environment = evalStatements(t, `
+		let x := 1;
+		let result := 0;
+		fn get() int {
+			return x;
+		}
+		fn shadow(x: int) int {
+			return x + get();
+		}
+		result = shadow(10);
+	`)
+
+	assertVariable(t, environment, "result", &IntegerObject{Value: 11})
+
+	environment = evalStatements(t, `
+		let x := 1;
+		let y := 0;
+		{
+			let x := x + 1;
+			y = x;
+		}
+	`)
+
+	assertVariable(t, environment, "x", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "y", &IntegerObject{Value: 2})
+
+	// a different type in every scope, so parse time and evaluation have to agree on which one is meant
+	environment = evalStatements(t, `
+		let x := 1;
+		fn describe(flag: bool) string {
+			let x := "function";
+			if flag {
+				let x := 2.5;
+				let inner: float = x;
+				return "if " + inner;
+			}
+			let i := 0;
+			while i++ < 1 {
+				let x := true;
+				let inner: bool = x;
+			}
+			let block: string = { let x := 3; x + 1 } + x;
+			return block;
+		}
+		let a: string = describe(true);
+		let b: string = describe(false);
+		let c: int = x;
+	`)
+
+	assertVariable(t, environment, "a", &StringObject{Value: "if 2.5"})
+	assertVariable(t, environment, "b", &StringObject{Value: "4function"})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 1})
+}
+
+func TestBlockExpression(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let t := 1;
+		let x := { let t := 3; t * 2 };
+		let y := {
+			let a := 2;
+			let b := { a + 1 };
+			a * b
+		};
+		let z := {};
+		let v := { t + 1; };
+		let nested := { { t + 1 } };
+		let chosen := {
+			let a := 2;
+			if a > 2 { a } elif a < 2 { -a } else { { a * 10 } }
+		};
+	`)
+
+	assertVariable(t, environment, "t", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "x", &IntegerObject{Value: 6})
+	assertVariable(t, environment, "y", &IntegerObject{Value: 6})
+	assertVariable(t, environment, "z", nil)
+	assertVariable(t, environment, "v", nil)
+	assertVariable(t, environment, "nested", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "chosen", &IntegerObject{Value: 20})
+}
+
+func TestIfExpression(t *testing.T) {
+
+	environment := evalStatements(t, `
+		fn sign(x: int) int {
+			return if x > 0 { 1 } else if x < 0 { -1 } else { 0 };
+		}
+		let a := 3;
+		let b := 5;
+		let max := if a > b { a } else { b };
+		let signs := sign(-4) + sign(0) * 10 + sign(9) * 100;
+		let text := if a == 3 {
+			let t := "three";
+			t
+		} else {
+			"other"
+		};
+		let sum := if a == 3 { 1 } else { 2 } + 3;
+	`)
+
+	assertVariable(t, environment, "max", &IntegerObject{Value: 5})
+	assertVariable(t, environment, "sum", &IntegerObject{Value: 4})
+	assertVariable(t, environment, "signs", &IntegerObject{Value: 99})
+	assertVariable(t, environment, "text", &StringObject{Value: "three"})
+}
+
+func TestElif(t *testing.T) {
+
+	environment := evalStatements(t, `
+		fn classify(x: int) string {
+			if x > 0 {
+				return "positive";
+			} elif x < 0 {
+				return "negative";
+			} else {
+				return "zero";
+			}
+		}
+		fn classifyElseIf(x: int) string {
+			if x > 0 {
+				return "positive";
+			} else if x < 0 {
+				return "negative";
+			} else {
+				return "zero";
+			}
+		}
+		let a := classify(-3) + classify(0) + classify(7);
+		let b := classifyElseIf(-3) + classifyElseIf(0) + classifyElseIf(7);
+		let c := if a == b { 1 } elif true { 2 } else { 3 };
+	`)
+
+	assertVariable(t, environment, "a", &StringObject{Value: "negativezeropositive"})
+	assertVariable(t, environment, "b", &StringObject{Value: "negativezeropositive"})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 1})
+
+	chain := &strings.Builder{}
+	chain.WriteString("let x := 499;\nlet y := -1;\nif x == 0 { y = 0; }")
+	for i := 1; i < 500; i++ {
+		_, _ = fmt.Fprintf(chain, " elif x == %d { y = %d; }", i, i)
+	}
+	environment = evalStatements(t, chain.String())
+	assertVariable(t, environment, "y", &IntegerObject{Value: 499})
+}
+
+func TestEmptyBodies(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let counter := 0;
+		let i := 0;
+		while i++ < 3 {}
+		while counter < 5 {
+			counter++;
+		}
+		let j := 0;
+		while j++ < 4;
+		if true {} else {}
+		if false; else;
+		{}
+	`)
+
+	assertVariable(t, environment, "i", &IntegerObject{Value: 4})
+	assertVariable(t, environment, "counter", &IntegerObject{Value: 5})
+	assertVariable(t, environment, "j", &IntegerObject{Value: 5})
+}
+
+func TestWhileElse(t *testing.T) {
+
+	environment := evalStatements(t, `
+		fn find(limit: int, target: int) string {
+			let i := 0;
+			while i < limit {
+				if i == target {
+					return "found";
+				}
+				i++;
+			} else {
+				return "empty";
+			}
+			return "missing";
+		}
+		let ran := 0;
+		let skipped := 0;
+		while ran < 3 {
+			ran++;
+		} else {
+			skipped = 1;
+		}
+		let never := 0;
+		while false {
+			never = 1;
+		} else {
+			never = 2;
+		}
+		let outer := 0;
+		while outer < 10 {
+			outer++;
+			while false {} else { break; }
+		}
+		let results := find(5, 2) + find(0, 2) + find(5, 7);
+	`)
+
+	assertVariable(t, environment, "ran", &IntegerObject{Value: 3})
+	assertVariable(t, environment, "skipped", &IntegerObject{Value: 0})
+	assertVariable(t, environment, "never", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "outer", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "results", &StringObject{Value: "foundemptymissing"})
+}
+
+func TestWhileScoping(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let counter := 0;
+		let iterations := 0;
+		let lastLocal := 0;
+		while counter < 10 {
+			let local := 0;
+			local++;
+			counter = counter + 2;
+			iterations++;
+			lastLocal = local;
+		}
+	`)
+
+	assertVariable(t, environment, "counter", &IntegerObject{Value: 10})
+	assertVariable(t, environment, "iterations", &IntegerObject{Value: 5})
+	assertVariable(t, environment, "lastLocal", &IntegerObject{Value: 1})
+}
+
+func TestBreakContinue(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let i := 0;
+		let sum := 0;
+		while true {
+			i++;
+			if i > 10 {
+				if true {
+					break;
+				}
+			}
+			if i == 3 {
+				continue;
+			} else {
+				{
+					sum = sum + i;
+				}
+			}
+		}
+
+		let outer := 0;
+		let inner := 0;
+		while outer < 3 {
+			outer++;
+			let j := 0;
+			while true {
+				if j++ == 2 { break; }
+				inner++;
+			}
+		}
+
+		fn find(limit: int) int {
+			let n := 0;
+			while true {
+				n++;
+				if n * n > limit {
+					return n;
+				}
+			}
+			return -1;
+		}
+		let found := find(50);
+	`)
+
+	assertVariable(t, environment, "i", &IntegerObject{Value: 11})
+	assertVariable(t, environment, "sum", &IntegerObject{Value: 52})
+	assertVariable(t, environment, "outer", &IntegerObject{Value: 3})
+	assertVariable(t, environment, "inner", &IntegerObject{Value: 6})
+	assertVariable(t, environment, "found", &IntegerObject{Value: 8})
+}
+
+func TestHoisting(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let doubled := double(4);
+		let even := isEven(10);
+		let odd := isEven(7);
+
+		fn isEven(n: int) bool {
+			if n == 0 {
+				return true;
+			}
+			return isOdd(n - 1);
+		}
+
+		fn isOdd(n: int) bool {
+			if n == 0 {
+				return false;
+			}
+			return isEven(n - 1);
+		}
+
+		fn double(n: int) int {
+			return n * 2;
+		}
+	`)
+
+	assertVariable(t, environment, "doubled", &IntegerObject{Value: 8})
+	assertVariable(t, environment, "even", &BooleanObject{Value: true})
+	assertVariable(t, environment, "odd", &BooleanObject{Value: false})
+}
+
+func TestUnresolvedIdentifier(t *testing.T) {
+
+	// declared for the type checker, but never bound in the environment
+	context := types.NewContext()
+	context.DefineMemberType("missing", &types.Int{})
+	program := parseCell(t, "let a := 1;\nlet b := a + missing;", context)
+
+	object := EvalStatements(program.Statements, ExtendEnvironment(NewEnvironment(context), program.Context))
+	err, isError := object.(*ErrorObject)
+	assert.Assert(t, isError)
+	assert.Equal(t, err.Message, "Cannot resolve identifier 'missing'")
+	assert.Equal(t, err.Token.Line, 2)
+	assert.Equal(t, err.Token.Col, 14)
+}
+
+func TestHoistedFunctionReadingEarlierVariable(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let x := 1;
+		let late := read();
+		fn read() int {
+			return x;
+		}
+	`)
+	assertVariable(t, environment, "late", &IntegerObject{Value: 1})
+}
+
+func TestOperatorMethods(t *testing.T) {
+
+	environment := evalStatements(t, `
+		fn (bool)::__add__(other: bool) bool {
+			return this || other;
+		}
+		fn (bool)::__lt__(other: bool) bool {
+			return !this && other;
+		}
+		fn (string)::__sub__(count: int) string {
+			let result := "";
+			let i := 0;
+			while i < count {
+				result = result + this;
+				i++;
+			}
+			return result;
+		}
+		fn (int)::__add__(other: int) int {
+			return 0;
+		}
+		let a := false + true;
+		let b := false < true;
+		let c := true < true;
+		let d := "ab" - 3;
+		let e := 1 + 2;
+	`)
+
+	assertVariable(t, environment, "a", &BooleanObject{Value: true})
+	assertVariable(t, environment, "b", &BooleanObject{Value: true})
+	assertVariable(t, environment, "c", &BooleanObject{Value: false})
+	assertVariable(t, environment, "d", &StringObject{Value: "ababab"})
+	assertVariable(t, environment, "e", &IntegerObject{Value: 3})
+}
+
+func TestEvaluationOrder(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let log := "";
+		fn f(name: string, value: int) int {
+			log = log + name;
+			return value;
+		}
+		fn sum(a: int, b: int, c: int) int {
+			return a + b + c;
+		}
+
+		let a := f("a", 1) + f("b", 2) * f("c", 3);
+		let infixOrder := log;
+
+		log = "";
+		let b := sum(f("a", 1), f("b", 2), f("c", 3));
+		let argumentOrder := log;
+
+		log = "";
+		let c := f("a", 0) == 1 && f("b", 0) == 0 || f("c", 0) == 0;
+		let shortCircuitOrder := log;
+
+		log = "";
+		let d := 0;
+		d = f("a", 1) + f("b", 1);
+		let assignmentOrder := log;
+
+		let i := 0;
+		let e := i++ + i++ * i++;
+	`)
+
+	assertVariable(t, environment, "a", &IntegerObject{Value: 7})
+	assertVariable(t, environment, "infixOrder", &StringObject{Value: "abc"})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 6})
+	assertVariable(t, environment, "argumentOrder", &StringObject{Value: "abc"})
+	assertVariable(t, environment, "c", &BooleanObject{Value: true})
+	assertVariable(t, environment, "shortCircuitOrder", &StringObject{Value: "ac"})
+	assertVariable(t, environment, "d", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "assignmentOrder", &StringObject{Value: "ab"})
+	assertVariable(t, environment, "e", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "i", &IntegerObject{Value: 3})
+}
+
+func TestAutomaticSemicolons(t *testing.T) {
+
+	environment := evalStatements(t, `
+		let a := 1
+		let b := a
+			+ 2
+			* 3
+		let c := 0
+		++c
+		let d := {
+			let e := 2
+			e * 3
+		}
+	`)
+
+	assertVariable(t, environment, "a", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 7})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "d", &IntegerObject{Value: 6})
+
+	// the example from the README
+	environment = evalStatements(t, `
+		let a := 1 +
+			2
+		let b := a
+			* 2
+		let c := b
+			- 1
+		let d := c
+		++d
+	`)
+
+	assertVariable(t, environment, "a", &IntegerObject{Value: 3})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 6})
+	assertVariable(t, environment, "c", &IntegerObject{Value: 5})
+	assertVariable(t, environment, "d", &IntegerObject{Value: 6})
+}
+
+func TestDefinedNames(t *testing.T) {
+
+	context := types.NewContext()
+	root := NewEnvironment(context)
+	context.DefineMemberType("x", &types.Int{})
+	root.DefineObject("x", &IntegerObject{Value: 0})
+
+	program := parseCell(t, "let b := \"b\"; let x := 1.5; fn a() int { return 1; }", context)
+	environment := ExtendEnvironment(root, program.Context)
+	EvalStatements(program.Statements, environment)
+
+	assert.DeepEqual(t, environment.DefinedNames(), []string{"a", "b", "x"})
+	assert.DeepEqual(t, root.DefinedNames(), []string{"x"})
+
+	definedType, ok := environment.GetDefinedType("x")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, definedType, &types.Float{})
+	definedType, ok = root.GetDefinedType("x")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, definedType, &types.Int{})
+	definedType, ok = environment.GetDefinedType("a")
+	assert.Assert(t, ok)
+	assert.Equal(t, definedType.ToString(), "fn() int")
+	_, ok = environment.GetDefinedType("c")
+	assert.Assert(t, !ok)
+}
+
+func TestSnapshot(t *testing.T) {
+
+	context := types.NewContext()
+	root := NewEnvironment(context)
+	context.DefineMemberType("x", &types.Int{})
+	root.DefineObject("x", &IntegerObject{Value: 0})
+
+	program := parseCell(t, "let a := 1; let b := 2;", context)
+	cellContext := program.Context
+	environment := ExtendEnvironment(root, cellContext)
+	EvalStatements(program.Statements, environment)
+
+	snapshot := environment.Snapshot()
+
+	program = parseCell(t, "let c := 3; a = 10; x = 5; { let a := 20; b = a; } fn (int)::double() int { return this * 2; }", cellContext)
+	EvalStatements(program.Statements, ExtendEnvironment(environment, program.Context))
+	environment.DefineObject("d", &IntegerObject{Value: 4})
+	cellContext.DefineMemberType("d", &types.Int{})
+	assertVariable(t, environment, "a", &IntegerObject{Value: 10})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 20})
+
+	assert.Assert(t, environment.Restore(snapshot))
+	assertVariable(t, environment, "a", &IntegerObject{Value: 1})
+	assertVariable(t, environment, "b", &IntegerObject{Value: 2})
+	assertVariable(t, environment, "x", &IntegerObject{Value: 0})
+	_, ok := environment.GetObject("d")
+	assert.Assert(t, !ok)
+
+	// definitions made after the snapshot can be repeated without conflicts
+	program = parseCell(t, "let d := 5; let e := a + d;", cellContext)
+	EvalStatements(program.Statements, ExtendEnvironment(environment, program.Context))
+
+	assert.Assert(t, !root.Restore(snapshot))
+}
+
+func parseCell(t *testing.T, input string, context *types.Context) *parser.Program {
+	theParser := parser.New(lexer.FromCode(input))
+	program, errors := theParser.ParseProgram(context)
+	for _, err := range errors {
+		t.Error(err.Message)
+	}
+	return program
+}
+
+func evalStatements(t *testing.T, input string) *Environment {
+
+	theLexer := lexer.FromCode(input)
+	theParser := parser.New(theLexer)
+
+	context := types.NewContext()
+	program, errors := theParser.ParseProgram(context)
+	for _, err := range errors {
+		t.Error(err.Message)
+	}
+
+	environment := ExtendEnvironment(NewEnvironment(context), program.Context)
+	if err, isError := EvalStatements(program.Statements, environment).(*ErrorObject); isError {
+		t.Error(err.Message)
+	}
+	return environment
+}
+
+func assertVariable(t *testing.T, environment *Environment, name string, expected Object) {
+	object, ok := environment.GetObject(name)
+	assert.Assert(t, ok, name)
+	assert.DeepEqual(t, object, expected)
+}
+
+func BenchmarkOuterVariableLookup(b *testing.B) {
+
+	input := `
+		let outer := 1;
+		{ { { {
+			let i := 0;
+			let sum := 0;
+			while i < 1000 {
+				sum = sum + outer;
+				i++;
+			}
+		} } } }
+	`
+
+	theLexer := lexer.FromCode(input)
+	theParser := parser.New(theLexer)
+	context := types.NewContext()
+	program, errors := theParser.ParseProgram(context)
+	if len(errors) > 0 {
+		b.Fatal(errors[0].Message)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Eval(program, NewEnvironment(context))
 	}
 }
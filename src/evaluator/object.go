@@ -2,7 +2,9 @@ package evaluator
 
 import (
 	"bananascript/src/parser"
+	"bananascript/src/token"
 	"bananascript/src/types"
+	"fmt"
 	"strconv"
 )
 
@@ -11,20 +13,75 @@ type ObjectType = string
 type Object interface {
 	ToString() string
 	Type() types.Type
+	Equals(other Object) bool
+}
+
+// like left.Equals(right), but also accepts nil objects (e.g. void results)
+func ObjectsEqual(left Object, right Object) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	return left.Equals(right)
+}
+
+// representation of an object for debugging, strings are quoted to tell them apart from other values
+func Inspect(object Object) string {
+	if stringObject, isString := object.(*StringObject); isString {
+		return strconv.Quote(stringObject.Value)
+	}
+	return object.ToString()
+}
+
+// implemented by objects that have a native Go representation
+type GoValuer interface {
+	GoValue() interface{}
+}
+
+// converts the result of a script for the host: void becomes nil, an error object is returned as error
+// and objects without a Go representation, like functions, cannot be converted
+func ToGoValue(object Object) (interface{}, error) {
+	switch object := object.(type) {
+	case nil:
+		return nil, nil
+	case *ErrorObject:
+		return nil, object
+	case GoValuer:
+		return object.GoValue(), nil
+	default:
+		return nil, fmt.Errorf("Cannot convert '%s' to a Go value", object.ToString())
+	}
 }
 
 type ErrorObject struct {
 	Message string
+	Token   *token.Token
 }
 
 func (errorObject *ErrorObject) ToString() string {
 	return "ERROR: " + errorObject.Message
 }
 
+// formats the error like errors.ParserError, the position is only known if the error has a token
+func (errorObject *ErrorObject) Error() string {
+	if errorObject.Token == nil {
+		return errorObject.Message
+	}
+	location := fmt.Sprintf("%d:%d", errorObject.Token.Line, errorObject.Token.Col)
+	if errorObject.Token.File != nil {
+		location = *errorObject.Token.File + ":" + location
+	}
+	return location + ": " + errorObject.Message
+}
+
 func (*ErrorObject) Type() types.Type {
 	return nil
 }
 
+func (errorObject *ErrorObject) Equals(other Object) bool {
+	object, isError := other.(*ErrorObject)
+	return isError && errorObject.Message == object.Message
+}
+
 type ReturnObject struct {
 	Object Object
 }
@@ -37,25 +94,98 @@ func (returnObject *ReturnObject) Type() types.Type {
 	return returnObject.Object.Type()
 }
 
+func (returnObject *ReturnObject) Equals(other Object) bool {
+	return ObjectsEqual(returnObject.Object, other)
+}
+
+// signals a call to exit, unwinds everything up to the runner like an error does
+type ExitObject struct {
+	Code int64
+}
+
+func (exitObject *ExitObject) ToString() string {
+	return "exit(" + strconv.FormatInt(exitObject.Code, 10) + ")"
+}
+
+func (*ExitObject) Type() types.Type {
+	return nil
+}
+
+func (exitObject *ExitObject) Equals(other Object) bool {
+	object, isExit := other.(*ExitObject)
+	return isExit && exitObject.Code == object.Code
+}
+
+// signals a break statement to the enclosing loop
+type BreakObject struct {
+}
+
+func (*BreakObject) ToString() string {
+	return "break"
+}
+
+func (*BreakObject) Type() types.Type {
+	return nil
+}
+
+func (*BreakObject) Equals(other Object) bool {
+	_, isBreak := other.(*BreakObject)
+	return isBreak
+}
+
+// signals a continue statement to the enclosing loop
+type ContinueObject struct {
+}
+
+func (*ContinueObject) ToString() string {
+	return "continue"
+}
+
+func (*ContinueObject) Type() types.Type {
+	return nil
+}
+
+func (*ContinueObject) Equals(other Object) bool {
+	_, isContinue := other.(*ContinueObject)
+	return isContinue
+}
+
 type Function interface {
 	Object
 	Execute(arguments []Object) Object
 	With(object Object) Function
+	Bind(argument Object) Function
+}
+
+// a function that needs the environment it is called from, for example to look up type members
+type ScopedFunction interface {
+	Function
+	ExecuteInScope(environment *Environment, arguments []Object) Object
 }
 
 type FunctionObject struct {
-	Environment  *Environment
-	Parameters   []*parser.Identifier
-	Body         *parser.BlockStatement
-	This         Object
-	Context      *types.Context
-	FunctionType types.Type
+	Environment    *Environment
+	Parameters     []*parser.Identifier
+	Body           *parser.BlockStatement
+	This           Object
+	ThisName       string // additional name of 'this', if the receiver is named
+	BoundArguments []Object
+	Context        *types.Context
+	FunctionType   types.Type
 }
 
 func (functionObject *FunctionObject) Execute(arguments []Object) Object {
+	arguments = BindArguments(functionObject.BoundArguments, arguments)
+	if len(arguments) != len(functionObject.Parameters) {
+		return NewError("Expected %d arguments, got %d", len(functionObject.Parameters)-len(functionObject.BoundArguments),
+			len(arguments)-len(functionObject.BoundArguments))
+	}
 	newEnvironment := ExtendEnvironment(functionObject.Environment, functionObject.Context)
 	if functionObject.This != nil {
 		newEnvironment.DefineObject("this", functionObject.This)
+		if functionObject.ThisName != "" {
+			newEnvironment.DefineObject(functionObject.ThisName, functionObject.This)
+		}
 	}
 	for i, argument := range arguments {
 		name := functionObject.Parameters[i].Value
@@ -71,12 +201,46 @@ func (functionObject *FunctionObject) Type() types.Type {
 	return functionObject.FunctionType
 }
 
+// functions are equal if they are the same definition in the same scope, bound to the same object
+func (functionObject *FunctionObject) Equals(other Object) bool {
+	object, isFunction := other.(*FunctionObject)
+	return isFunction && functionObject.Body == object.Body && functionObject.Environment == object.Environment &&
+		ObjectsEqual(functionObject.This, object.This) && ArgumentsEqual(functionObject.BoundArguments, object.BoundArguments)
+}
+
+func ArgumentsEqual(left []Object, right []Object) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if !ObjectsEqual(left[i], right[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (functionObject *FunctionObject) With(object Object) Function {
 	newFunction := *functionObject
 	newFunction.This = object
 	return &newFunction
 }
 
+// returns a copy of the function with its first remaining parameter fixed to the given argument
+func (functionObject *FunctionObject) Bind(argument Object) Function {
+	newFunction := *functionObject
+	newFunction.BoundArguments = BindArguments(functionObject.BoundArguments, []Object{argument})
+	newFunction.FunctionType = functionObject.FunctionType.(*types.Function).Bind()
+	return &newFunction
+}
+
+func BindArguments(bound []Object, arguments []Object) []Object {
+	if len(bound) == 0 {
+		return arguments
+	}
+	return append(append(make([]Object, 0, len(bound)+len(arguments)), bound...), arguments...)
+}
+
 func (*FunctionObject) ToString() string {
 	return "[Function]"
 }
@@ -93,10 +257,40 @@ func (*StringObject) Type() types.Type {
 	return &types.String{}
 }
 
+func (stringObject *StringObject) GoValue() interface{} {
+	return stringObject.Value
+}
+
+func (stringObject *StringObject) Equals(other Object) bool {
+	object, isString := other.(*StringObject)
+	return isString && stringObject.Value == object.Value
+}
+
+// integer objects are immutable, so small values can be shared instead of allocated over and over
 type IntegerObject struct {
 	Value int64
 }
 
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 255
+)
+
+var cachedIntegers = func() []*IntegerObject {
+	integers := make([]*IntegerObject, maxCachedInteger-minCachedInteger+1)
+	for i := range integers {
+		integers[i] = &IntegerObject{Value: int64(i + minCachedInteger)}
+	}
+	return integers
+}()
+
+func NewInteger(value int64) *IntegerObject {
+	if value >= minCachedInteger && value <= maxCachedInteger {
+		return cachedIntegers[value-minCachedInteger]
+	}
+	return &IntegerObject{Value: value}
+}
+
 func (integerObject *IntegerObject) ToString() string {
 	return strconv.FormatInt(integerObject.Value, 10)
 }
@@ -105,6 +299,21 @@ func (*IntegerObject) Type() types.Type {
 	return &types.Int{}
 }
 
+func (integerObject *IntegerObject) GoValue() interface{} {
+	return integerObject.Value
+}
+
+func (integerObject *IntegerObject) Equals(other Object) bool {
+	switch other := other.(type) {
+	case *IntegerObject:
+		return integerObject.Value == other.Value
+	case *FloatObject:
+		return float64(integerObject.Value) == other.Value
+	default:
+		return false
+	}
+}
+
 type FloatObject struct {
 	Value float64
 }
@@ -117,6 +326,22 @@ func (*FloatObject) Type() types.Type {
 	return &types.Float{}
 }
 
+func (floatObject *FloatObject) GoValue() interface{} {
+	return floatObject.Value
+}
+
+// compared by value, so NaN is never equal to itself
+func (floatObject *FloatObject) Equals(other Object) bool {
+	switch other := other.(type) {
+	case *IntegerObject:
+		return floatObject.Value == float64(other.Value)
+	case *FloatObject:
+		return floatObject.Value == other.Value
+	default:
+		return false
+	}
+}
+
 type BooleanObject struct {
 	Value bool
 }
@@ -129,6 +354,15 @@ func (*BooleanObject) Type() types.Type {
 	return &types.Bool{}
 }
 
+func (booleanObject *BooleanObject) GoValue() interface{} {
+	return booleanObject.Value
+}
+
+func (booleanObject *BooleanObject) Equals(other Object) bool {
+	object, isBool := other.(*BooleanObject)
+	return isBool && booleanObject.Value == object.Value
+}
+
 type NullObject struct {
 }
 
@@ -139,3 +373,12 @@ func (*NullObject) ToString() string {
 func (*NullObject) Type() types.Type {
 	return &types.Null{}
 }
+
+func (*NullObject) GoValue() interface{} {
+	return nil
+}
+
+func (*NullObject) Equals(other Object) bool {
+	_, isNull := other.(*NullObject)
+	return isNull
+}
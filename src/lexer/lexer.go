@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type Lexer struct {
@@ -37,6 +39,10 @@ func FromCode(input string) *Lexer {
 	return &Lexer{input: []rune(input), line: 1, Errors: make([]*errors.ParserError, 0)}
 }
 
+func (lexer *Lexer) Source() string {
+	return string(lexer.input)
+}
+
 func (lexer *Lexer) current() rune {
 	if lexer.position < len(lexer.input) {
 		return lexer.input[lexer.position]
@@ -48,7 +54,7 @@ func (lexer *Lexer) current() rune {
 func (lexer *Lexer) consume() rune {
 	ch := lexer.current()
 	lexer.position++
-	if ch == '\n' {
+	if ch == '\n' || (ch == '\r' && lexer.current() != '\n') {
 		lexer.line++
 		lexer.col = 0
 	} else {
@@ -67,6 +73,11 @@ func (lexer *Lexer) peek() rune {
 
 func (lexer *Lexer) NextToken() *token.Token {
 
+	// a shebang line is only allowed at the very start of a file
+	if lexer.position == 0 && lexer.current() == '#' && lexer.peek() == '!' {
+		lexer.eatLine()
+	}
+
 	lexer.eatWhitespace()
 	char := lexer.consume()
 	startCol := lexer.col
@@ -84,12 +95,18 @@ func (lexer *Lexer) NextToken() *token.Token {
 		if lexer.current() == '+' {
 			lexer.consume()
 			return lexer.newToken(token.Increment, "", startCol)
+		} else if lexer.current() == '=' {
+			lexer.consume()
+			return lexer.newToken(token.PlusAssign, "", startCol)
 		}
 		return lexer.newToken(token.Plus, "", startCol)
 	case '-':
 		if lexer.current() == '-' {
 			lexer.consume()
 			return lexer.newToken(token.Decrement, "", startCol)
+		} else if lexer.current() == '=' {
+			lexer.consume()
+			return lexer.newToken(token.MinusAssign, "", startCol)
 		}
 		return lexer.newToken(token.Minus, "", startCol)
 	case '/':
@@ -99,10 +116,30 @@ func (lexer *Lexer) NextToken() *token.Token {
 		} else if lexer.current() == '*' {
 			lexer.eatComment()
 			return lexer.NextToken()
+		} else if lexer.current() == '=' {
+			lexer.consume()
+			return lexer.newToken(token.SlashAssign, "", startCol)
 		}
 		return lexer.newToken(token.Slash, "", startCol)
 	case '*':
+		if lexer.current() == '*' {
+			lexer.consume()
+			if lexer.current() == '=' {
+				lexer.consume()
+				return lexer.newToken(token.PowerAssign, "", startCol)
+			}
+			return lexer.newToken(token.Power, "", startCol)
+		} else if lexer.current() == '=' {
+			lexer.consume()
+			return lexer.newToken(token.StarAssign, "", startCol)
+		}
 		return lexer.newToken(token.Star, "", startCol)
+	case '%':
+		if lexer.current() == '=' {
+			lexer.consume()
+			return lexer.newToken(token.PercentAssign, "", startCol)
+		}
+		return lexer.newToken(token.Percent, "", startCol)
 	case '<':
 		if lexer.current() == '=' {
 			lexer.consume()
@@ -150,6 +187,8 @@ func (lexer *Lexer) NextToken() *token.Token {
 			return lexer.newToken(token.Define, "", startCol)
 		}
 		return lexer.newToken(token.Colon, "", startCol)
+	case '@':
+		return lexer.newToken(token.At, "", startCol)
 	case '(':
 		return lexer.newToken(token.LParen, "", startCol)
 	case ')':
@@ -160,6 +199,8 @@ func (lexer *Lexer) NextToken() *token.Token {
 		return lexer.newToken(token.RBrace, "", startCol)
 	case '"':
 		return lexer.parseString(startCol)
+	case '`':
+		return lexer.parseEscapedIdent(startCol)
 	}
 
 	if isIdent(char) {
@@ -174,24 +215,7 @@ func (lexer *Lexer) NextToken() *token.Token {
 		return lexer.newToken(token.Ident, ident, startCol)
 
 	} else if isDigit(char) {
-		start := lexer.position - 1
-		isFloat := false
-		for isDigit(lexer.current()) {
-			lexer.consume()
-		}
-		if lexer.current() == '.' {
-			isFloat = true
-			lexer.consume()
-			for isDigit(lexer.current()) {
-				lexer.consume()
-			}
-		}
-		literal := string(lexer.input[start:lexer.position])
-		if isFloat {
-			return lexer.newToken(token.FloatLiteral, literal, startCol)
-		} else {
-			return lexer.newToken(token.IntLiteral, literal, startCol)
-		}
+		return lexer.parseNumber(startCol)
 	} else {
 		if !lexer.lastWasIllegal {
 			lexer.error(startCol, "Illegal token")
@@ -200,22 +224,108 @@ func (lexer *Lexer) NextToken() *token.Token {
 	}
 }
 
+// parses an int or float literal starting at its first digit, digits can be grouped by single underscores
+func (lexer *Lexer) parseNumber(startCol int) *token.Token {
+	start := lexer.position - 1
+	isFloat := false
+	lexer.eatDigits()
+	if lexer.current() == '.' {
+		isFloat = true
+		lexer.consume()
+		lexer.eatDigits()
+	}
+	if lexer.isExponent() {
+		isFloat = true
+		lexer.consume() // e
+		if lexer.current() == '+' || lexer.current() == '-' {
+			lexer.consume()
+		}
+		lexer.eatDigits()
+	}
+
+	raw := lexer.input[start:lexer.position]
+	for i, char := range raw {
+		if char == '_' && (i+1 == len(raw) || !isDigit(raw[i-1]) || !isDigit(raw[i+1])) {
+			lexer.error(startCol, "Misplaced digit separator (%s)", string(raw))
+			break
+		}
+	}
+
+	literal := strings.ReplaceAll(string(raw), "_", "")
+	if isFloat {
+		return lexer.newToken(token.FloatLiteral, literal, startCol)
+	}
+	return lexer.newToken(token.IntLiteral, literal, startCol)
+}
+
+func (lexer *Lexer) eatDigits() {
+	for isDigit(lexer.current()) || lexer.current() == '_' {
+		lexer.consume()
+	}
+}
+
+// an 'e' after a number only starts an exponent if digits follow, optionally after a sign
+func (lexer *Lexer) isExponent() bool {
+	if lexer.current() != 'e' && lexer.current() != 'E' {
+		return false
+	}
+	isDigitOrSeparator := func(char rune) bool {
+		return isDigit(char) || char == '_'
+	}
+	if isDigitOrSeparator(lexer.peek()) {
+		return true
+	}
+	return (lexer.peek() == '+' || lexer.peek() == '-') && lexer.position+2 < len(lexer.input) &&
+		isDigitOrSeparator(lexer.input[lexer.position+2])
+}
+
+// identifiers in backticks can have the name of a keyword
+func (lexer *Lexer) parseEscapedIdent(startCol int) *token.Token {
+	start := lexer.position
+	for lexer.current() != '`' && lexer.current() != 0 && !isLineBreak(lexer.current()) {
+		lexer.consume()
+	}
+	ident := string(lexer.input[start:lexer.position])
+	if lexer.current() != '`' {
+		lexer.error(startCol, "Unclosed identifier")
+		return lexer.newToken(token.Illegal, ident, startCol)
+	}
+	lexer.consume() // `
+
+	valid := ident != ""
+	for i, char := range ident {
+		valid = valid && (isIdent(char) || (i > 0 && isDigit(char)))
+	}
+	if !valid {
+		lexer.error(startCol, "Invalid identifier (%s)", ident)
+		return lexer.newToken(token.Illegal, ident, startCol)
+	}
+	return lexer.newToken(token.Ident, ident, startCol)
+}
+
 func (lexer *Lexer) parseString(stringStartCol int) *token.Token {
 	literal := ""
 
 parseChar:
 	for {
+		// the line break is left to the next token, so the error and the literal stay on the opening line
+		if isLineBreak(lexer.current()) || lexer.current() == 0 {
+			lexer.error(stringStartCol, "Unterminated string literal")
+			return lexer.newToken(token.StringLiteral, literal, stringStartCol)
+		}
 		current := lexer.consume()
 		startCol := lexer.col
-		if current == '"' || current == '\n' || current == 0 {
-			if current == '\n' || current == 0 {
-				lexer.error(startCol+1, "Unclosed string literal")
-			}
+		if current == '"' {
 			return lexer.newToken(token.StringLiteral, literal, stringStartCol)
 		}
 		toAdd := string(current)
 		if current == '\\' {
 			switch next := lexer.consume(); next {
+			case '\n', '\r': // an escaped line break continues the string on the next line
+				if next == '\r' && lexer.current() == '\n' {
+					lexer.consume()
+				}
+				toAdd = ""
 			case '\\':
 				toAdd = "\\"
 			case '"':
@@ -236,24 +346,24 @@ parseChar:
 				toAdd = "\t"
 			case 'v':
 				toAdd = "\v"
-			case 'x':
+			case 'x': // exactly two digits, longer code points need \u{...}
 				hex := ""
-				for {
+				for i := 0; i < 2; i++ {
 					current := lexer.current()
-					if isHex(current) {
-						hex += string(current)
-						lexer.consume()
-					} else {
-						break
+					if !isHex(current) {
+						lexer.error(startCol, "Invalid hexadecimal (%s)", hex)
+						continue parseChar
 					}
+					lexer.consume()
+					hex += string(current)
 				}
-				value, err := strconv.ParseInt(hex, 16, 64)
-				if err != nil {
-					lexer.error(startCol, "Invalid hexadecimal (%s)", hex)
-				} else {
-					toAdd = string(rune(value))
-				}
+				value, _ := strconv.ParseInt(hex, 16, 16)
+				toAdd = string(rune(value))
 			case 'u', 'U':
+				if next == 'u' && lexer.current() == '{' {
+					toAdd = lexer.parseUnicodeBraces(startCol)
+					break
+				}
 				nDigits := 4
 				if next == 'U' {
 					nDigits = 8
@@ -269,17 +379,17 @@ parseChar:
 					hex += string(current)
 				}
 				value, err := strconv.ParseInt(hex, 16, nDigits*8)
-				if err != nil {
+				if err != nil || !utf8.ValidRune(rune(value)) || value > utf8.MaxRune {
 					lexer.error(startCol, "Invalid unicode sequence (%s)", hex)
 				} else {
 					toAdd = string(rune(value))
 				}
 			default:
-				if next >= '0' && next <= '8' {
+				if next >= '0' && next <= '7' {
 					octal := string(next)
 					for i := 0; i < 2; i++ {
 						current := lexer.current()
-						if current >= '0' && current <= '8' {
+						if current >= '0' && current <= '7' {
 							octal += string(current)
 							lexer.consume()
 						} else {
@@ -292,14 +402,36 @@ parseChar:
 					} else {
 						toAdd = string(rune(value))
 					}
+				} else {
+					lexer.error(startCol, "Invalid escape sequence")
 				}
-				lexer.error(startCol, "Invalid escape sequence")
 			}
 		}
 		literal += toAdd
 	}
 }
 
+// parses the code point of a \u{...} escape, starting at the opening brace
+func (lexer *Lexer) parseUnicodeBraces(startCol int) string {
+	lexer.consume() // {
+	hex := ""
+	for isHex(lexer.current()) {
+		hex += string(lexer.consume())
+	}
+	if lexer.current() != '}' {
+		lexer.error(startCol, "Unclosed unicode sequence")
+		return ""
+	}
+	lexer.consume() // }
+
+	value, err := strconv.ParseInt(hex, 16, 32)
+	if err != nil || len(hex) > 6 || !utf8.ValidRune(rune(value)) {
+		lexer.error(startCol, "Invalid unicode sequence (%s)", hex)
+		return ""
+	}
+	return string(rune(value))
+}
+
 func (lexer *Lexer) eatWhitespace() {
 	for isWhitespace(lexer.current()) {
 		lexer.consume()
@@ -307,7 +439,7 @@ func (lexer *Lexer) eatWhitespace() {
 }
 
 func (lexer *Lexer) eatLine() {
-	for lexer.current() != 0 && lexer.current() != '\n' {
+	for lexer.current() != 0 && !isLineBreak(lexer.current()) {
 		lexer.consume()
 	}
 }
@@ -324,6 +456,11 @@ func isWhitespace(char rune) bool {
 	return char == ' ' || char == '\t' || char == '\r' || char == '\v' || char == '\f' || char == '\n'
 }
 
+// \r\n and a lone \r end a line just like \n
+func isLineBreak(char rune) bool {
+	return char == '\n' || char == '\r'
+}
+
 func isIdent(char rune) bool {
 	return (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') || char == '_'
 }
@@ -19,6 +19,25 @@ func TestLexer(t *testing.T) {
 			token.IntLiteral, token.Star, token.IntLiteral, token.RParen},
 	)
 
+	assertTypes(t,
+		"a % b ** c; a += 1; a -= 1; a *= 1; a /= 1; a %= 1; a **= 1;",
+		[]token.Type{token.Ident, token.Percent, token.Ident, token.Power, token.Ident, token.Semi,
+			token.Ident, token.PlusAssign, token.IntLiteral, token.Semi, token.Ident, token.MinusAssign, token.IntLiteral, token.Semi,
+			token.Ident, token.StarAssign, token.IntLiteral, token.Semi, token.Ident, token.SlashAssign, token.IntLiteral, token.Semi,
+			token.Ident, token.PercentAssign, token.IntLiteral, token.Semi, token.Ident, token.PowerAssign, token.IntLiteral, token.Semi},
+	)
+
+	assertTypes(t,
+		"a in b `in`",
+		[]token.Type{token.Ident, token.In, token.Ident, token.Ident},
+	)
+	assert.Equal(t, token.In.ToString(), "IN")
+
+	assertTypes(t,
+		"@deprecated fn",
+		[]token.Type{token.At, token.Ident, token.Func},
+	)
+
 	assertTypes(t,
 		"0.5 11 5.",
 		[]token.Type{token.FloatLiteral, token.IntLiteral, token.FloatLiteral},
@@ -64,7 +83,7 @@ func TestLexer(t *testing.T) {
 	)
 
 	assertToken(t,
-		"\"\\x1F60A\"",
+		"\"\\u{1F60A}\"",
 		&token.Token{
 			Type:    token.StringLiteral,
 			Literal: "😊",
@@ -97,6 +116,148 @@ func TestLexer(t *testing.T) {
 	)
 }
 
+func TestEscapes(t *testing.T) {
+	assertString(t, "\"\\u{1F600}\"", "😀")
+	assertString(t, "\"\\u{41}\\u{e9}\"", "Aé")
+	assertString(t, "\"\\x41\"", "A")
+	assertString(t, "\"\\x41BC\"", "ABC")
+	assertString(t, "\"\\x4a\\x4A\"", "JJ")
+	assertString(t, "\"\\101\\u0042\"", "AB")
+
+	assertLexerError(t, "\"\\u{}\"", "Invalid unicode sequence ()")
+	assertLexerError(t, "\"\\u{110000}\"", "Invalid unicode sequence (110000)")
+	assertLexerError(t, "\"\\u{D800}\"", "Invalid unicode sequence (D800)")
+	assertLexerError(t, "\"\\u{41\"", "Unclosed unicode sequence")
+	assertLexerError(t, "\"\\x\"", "Invalid hexadecimal ()")
+	assertLexerError(t, "\"\\x4\"", "Invalid hexadecimal (4)")
+	assertLexerError(t, "\"\\x4G\"", "Invalid hexadecimal (4)")
+	assertLexerError(t, "\"\\q\"", "Invalid escape sequence")
+
+	lexer := FromCode("s := \"ab\\x4\";")
+	for nextToken := lexer.NextToken(); nextToken.Type != token.EOF; nextToken = lexer.NextToken() {
+	}
+	assert.Equal(t, len(lexer.Errors), 1)
+	assert.Equal(t, lexer.Errors[0].Message, "Invalid hexadecimal (4)")
+	assert.Equal(t, lexer.Errors[0].Col, 9)
+}
+
+func TestShebang(t *testing.T) {
+	assertTypes(t, "#!/usr/bin/env bananascript\nlet a := 1;",
+		[]token.Type{token.Let, token.Ident, token.Define, token.IntLiteral, token.Semi})
+	assertTypes(t, "#!bananascript", []token.Type{})
+
+	lexer := FromCode("let a := 1;\n#!/usr/bin/env bananascript")
+	for nextToken := lexer.NextToken(); nextToken.Type != token.EOF; nextToken = lexer.NextToken() {
+	}
+	assert.Equal(t, len(lexer.Errors), 1)
+	assert.Equal(t, lexer.Errors[0].Message, "Illegal token")
+	assert.Equal(t, lexer.Errors[0].Line, 2)
+
+	assertLexerError(t, " #!/usr/bin/env bananascript", "Illegal token")
+}
+
+func TestLineEndings(t *testing.T) {
+	unix := lexAll(t, "let a := 1;\nlet b := \"x\"; // comment\n\n  a = b;\n")
+	assert.DeepEqual(t, lexAll(t, "let a := 1;\r\nlet b := \"x\"; // comment\r\n\r\n  a = b;\r\n"), unix)
+	assert.DeepEqual(t, lexAll(t, "let a := 1;\rlet b := \"x\"; // comment\r\r  a = b;\r"), unix)
+
+	assignee := unix[len(unix)-4]
+	assert.Equal(t, assignee.Literal, "a")
+	assert.Equal(t, assignee.Line, 4)
+	assert.Equal(t, assignee.Col, 3)
+
+	lexer := FromCode("let a := \"abc\r\nlet b := 1;")
+	theToken := lexer.NextToken()
+	for theToken.Type != token.StringLiteral {
+		theToken = lexer.NextToken()
+	}
+	assert.Equal(t, theToken.Literal, "abc")
+	assert.Equal(t, len(lexer.Errors), 1)
+	assert.Equal(t, lexer.Errors[0].Message, "Unterminated string literal")
+	assert.Equal(t, lexer.NextToken().Line, 2)
+
+	assertString(t, "\"a\\r\\nb\"", "a\r\nb")
+}
+
+func TestNumbers(t *testing.T) {
+	assertToken(t, "1_000", &token.Token{Type: token.IntLiteral, Literal: "1000", Line: 1, Col: 1})
+	assertToken(t, "1_000.000_1", &token.Token{Type: token.FloatLiteral, Literal: "1000.0001", Line: 1, Col: 1})
+	assertToken(t, "1_0e1_0", &token.Token{Type: token.FloatLiteral, Literal: "10e10", Line: 1, Col: 1})
+	assertToken(t, "2.5E-3", &token.Token{Type: token.FloatLiteral, Literal: "2.5E-3", Line: 1, Col: 1})
+	assertToken(t, "3e+2", &token.Token{Type: token.FloatLiteral, Literal: "3e+2", Line: 1, Col: 1})
+	assertToken(t, "5.", &token.Token{Type: token.FloatLiteral, Literal: "5.", Line: 1, Col: 1})
+	assertTypes(t, "1else", []token.Type{token.IntLiteral, token.Else})
+	assertTypes(t, "2e", []token.Type{token.IntLiteral, token.Ident})
+	assertTypes(t, "_1", []token.Type{token.Ident})
+
+	for _, input := range []string{"1_", "1__0", "1_.5", "1._5", "1.5_", "1_e5", "1e_5", "1e-_5", "1e5_"} {
+		assertLexerError(t, input, "Misplaced digit separator ("+input+")")
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	assertLexerError(t, "\"abc", "Unterminated string literal")
+	assertLexerError(t, "\"abc\ndef\"", "Unterminated string literal")
+	assertLexerError(t, "\"abc\\\"", "Unterminated string literal")
+
+	lexer := FromCode("let a := 1;\nlet b := \"ab\ncd;")
+	tokens := make([]*token.Token, 0)
+	for theToken := lexer.NextToken(); theToken.Type != token.EOF; theToken = lexer.NextToken() {
+		tokens = append(tokens, theToken)
+	}
+	assert.Equal(t, len(lexer.Errors), 1)
+	assert.Equal(t, lexer.Errors[0].Line, 2)
+	assert.Equal(t, lexer.Errors[0].Col, 10)
+	assert.DeepEqual(t, tokens[8], &token.Token{Type: token.StringLiteral, Literal: "ab", Line: 2, Col: 10})
+	assert.DeepEqual(t, tokens[9], &token.Token{Type: token.Ident, Literal: "cd", Line: 3, Col: 1})
+
+	assertString(t, "\"ab\\\ncd\"", "abcd")
+	assertString(t, "\"ab\\\r\ncd\"", "abcd")
+	assertString(t, "\"ab\\\rcd\"", "abcd")
+}
+
+func TestEscapedIdentifiers(t *testing.T) {
+	assertToken(t, "`let`", &token.Token{Type: token.Ident, Literal: "let", Line: 1, Col: 1})
+	assertToken(t, "`abc1`", &token.Token{Type: token.Ident, Literal: "abc1", Line: 1, Col: 1})
+	assertTypes(t, "let `type` := `while`;", []token.Type{token.Let, token.Ident, token.Define, token.Ident, token.Semi})
+	assertLexerError(t, "`let", "Unclosed identifier")
+	assertLexerError(t, "`let\n`", "Unclosed identifier")
+	assertLexerError(t, "``", "Invalid identifier ()")
+	assertLexerError(t, "`1a`", "Invalid identifier (1a)")
+	assertLexerError(t, "`a b`", "Invalid identifier (a b)")
+}
+
+func TestElif(t *testing.T) {
+	assertTypes(t, "} elif x {", []token.Type{token.RBrace, token.Elif, token.Ident, token.LBrace})
+	assertToken(t, "elif", &token.Token{Type: token.Elif, Line: 1, Col: 1})
+	assertToken(t, "elifx", &token.Token{Type: token.Ident, Literal: "elifx", Line: 1, Col: 1})
+}
+
+func lexAll(t *testing.T, input string) []*token.Token {
+	lexer := FromCode(input)
+	tokens := make([]*token.Token, 0)
+	for nextToken := lexer.NextToken(); nextToken.Type != token.EOF; nextToken = lexer.NextToken() {
+		tokens = append(tokens, nextToken)
+	}
+	assert.Equal(t, len(lexer.Errors), 0, input)
+	return tokens
+}
+
+func assertString(t *testing.T, input string, expected string) {
+	lexer := FromCode(input)
+	theToken := lexer.NextToken()
+	assert.Equal(t, len(lexer.Errors), 0, input)
+	assert.Equal(t, theToken.Literal, expected)
+}
+
+func assertLexerError(t *testing.T, input string, message string) {
+	lexer := FromCode(input)
+	lexer.NextToken()
+	assert.Equal(t, len(lexer.Errors), 1, input)
+	assert.Equal(t, lexer.Errors[0].Message, message)
+	assert.Equal(t, lexer.Errors[0].Line, 1)
+}
+
 func assertTypes(t *testing.T, input string, expectedTypes []token.Type) {
 
 	lexer := FromCode(input)
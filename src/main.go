@@ -1,13 +1,9 @@
 package main
 
 import (
-	"bananascript/src/builtins"
-	"bananascript/src/evaluator"
-	"bananascript/src/lexer"
-	"bananascript/src/parser"
 	"bananascript/src/repl"
+	"bananascript/src/runner"
 	"flag"
-	"fmt"
 	"github.com/gookit/color"
 	"os"
 )
@@ -15,6 +11,9 @@ import (
 func main() {
 	help := flag.Bool("help", false, "show help")
 	forceColor := flag.Bool("forceColor", false, "force colorized output")
+	optimize := flag.Bool("optimize", false, "fold constant expressions before evaluation")
+	test := flag.Bool("test", false, "run file as test and report assertions")
+	warnUnused := flag.Bool("warnUnused", false, "warn about variables that are declared but never read")
 	flag.Parse()
 
 	if *help {
@@ -27,38 +26,11 @@ func main() {
 	}
 
 	if flag.NArg() > 0 {
-		runFile(flag.Arg(0))
-	} else {
-		repl.Start()
-	}
-}
-
-func runFile(fileName string) {
-	theLexer, err := lexer.FromFile(fileName)
-	if err != nil {
-		fmt.Println(err.Error())
-		return
-	}
-
-	theParser := parser.New(theLexer)
-	context, environment := builtins.NewContextAndEnvironment()
-
-	program, errors := theParser.ParseProgram(context)
-	if len(errors) > 0 {
-		errorStr := "Encountered %d error"
-		if len(errors) > 1 {
-			errorStr += "s"
+		if *test {
+			os.Exit(runner.Test(flag.Arg(0), os.Stdout))
 		}
-		errorStr += ":"
-		fmt.Println(color.FgRed.Sprintf(errorStr, len(errors)))
-		for _, err := range errors {
-			fmt.Println(err.PrettyPrint(true))
-		}
-		os.Exit(1)
+		os.Exit(runner.Run(flag.Arg(0), *optimize, *warnUnused, os.Stdout))
 	} else {
-		object := evaluator.Eval(program, environment)
-		if err, isError := object.(*evaluator.ErrorObject); isError {
-			fmt.Println(err.Message)
-		}
+		repl.Start()
 	}
 }
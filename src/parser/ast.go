@@ -5,6 +5,7 @@ import (
 	"bananascript/src/types"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type Node interface {
@@ -52,6 +53,12 @@ func (invalidExpression *InvalidExpression) ToString() string {
 type Identifier struct {
 	IdentToken *token.Token
 	Value      string
+	depth      int
+	resolved   bool
+}
+
+func (identifier *Identifier) Depth() (int, bool) {
+	return identifier.depth, identifier.resolved
 }
 
 func (identifier *Identifier) Token() *token.Token {
@@ -72,9 +79,22 @@ func (parameter *Parameter) ToString() string {
 	return parameter.Name.Value + ": " + parameter.Type.ToString()
 }
 
+type EmptyStatement struct {
+	SemiToken *token.Token
+}
+
+func (emptyStatement *EmptyStatement) Token() *token.Token {
+	return emptyStatement.SemiToken
+}
+
+func (emptyStatement *EmptyStatement) ToString() string {
+	return ";"
+}
+
 type ExpressionStatement struct {
 	FirstToken *token.Token
 	Expression Expression
+	SemiToken  *token.Token // nil if the statement is not terminated by ';'
 }
 
 func (expressionStatement *ExpressionStatement) Token() *token.Token {
@@ -111,7 +131,7 @@ func (infixExpression *InfixExpression) Token() *token.Token {
 }
 
 func (infixExpression *InfixExpression) ToString() string {
-	return "(" + infixExpression.Left.ToString() + " " + infixExpression.Operator.ToString() + " " +
+	return "(" + infixExpression.Left.ToString() + " " + infixExpression.Operator.OperatorString() + " " +
 		infixExpression.Right.ToString() + ")"
 }
 
@@ -131,9 +151,10 @@ func (assignmentExpression *AssignmentExpression) ToString() string {
 }
 
 type CallExpression struct {
-	ParenToken *token.Token
-	Function   Expression
-	Arguments  []Expression
+	ParenToken   *token.Token
+	Function     Expression
+	Arguments    []Expression
+	functionType *types.Function // resolved signature, set by the type checker
 }
 
 func (callExpression *CallExpression) Token() *token.Token {
@@ -234,8 +255,10 @@ type FunctionDefinitionStatement struct {
 	Body            *BlockStatement
 	FunctionContext *types.Context
 	ThisType        types.Type
+	ThisName        *Identifier // receiver name in 'fn (name: type)::method()', nil if only 'this' is used
 	ReturnType      types.Type
 	FunctionType    *types.Function
+	Annotations     []*Identifier
 }
 
 func (funcStatement *FunctionDefinitionStatement) Token() *token.Token {
@@ -254,10 +277,11 @@ func (funcStatement *FunctionDefinitionStatement) ToString() string {
 }
 
 type LetStatement struct {
-	LetToken *token.Token
-	Name     *Identifier
-	Type     types.Type
-	Value    Expression
+	LetToken    *token.Token
+	Name        *Identifier
+	Type        types.Type
+	Value       Expression
+	Annotations []*Identifier
 }
 
 func (letStatement *LetStatement) Token() *token.Token {
@@ -268,6 +292,25 @@ func (letStatement *LetStatement) ToString() string {
 	return fmt.Sprintf("let %s: %s = %s;", letStatement.Name.Value, letStatement.Type.ToString(), letStatement.Value.ToString())
 }
 
+// a let with several comma separated declarations, like let a := 1, b := 2;
+type MultiLetStatement struct {
+	LetToken     *token.Token
+	Declarations []*LetStatement
+}
+
+func (multiLetStatement *MultiLetStatement) Token() *token.Token {
+	return multiLetStatement.LetToken
+}
+
+func (multiLetStatement *MultiLetStatement) ToString() string {
+	declarations := make([]string, 0)
+	for _, declaration := range multiLetStatement.Declarations {
+		declarations = append(declarations, fmt.Sprintf("%s: %s = %s", declaration.Name.Value,
+			declaration.Type.ToString(), declaration.Value.ToString()))
+	}
+	return "let " + strings.Join(declarations, ", ") + ";"
+}
+
 type ReturnStatement struct {
 	ReturnToken *token.Token
 	Expression  Expression
@@ -300,6 +343,37 @@ func (blockStatement *BlockStatement) ToString() string {
 	return result + "\n}"
 }
 
+type BlockExpression struct {
+	Block     *BlockStatement
+	Context   *types.Context
+	ValueType types.Type
+}
+
+func (blockExpression *BlockExpression) Token() *token.Token {
+	return blockExpression.Block.LBraceToken
+}
+
+func (blockExpression *BlockExpression) ToString() string {
+	return blockExpression.Block.ToString()
+}
+
+type IfExpression struct {
+	IfToken     *token.Token
+	Condition   Expression
+	Consequence *BlockExpression
+	Alternative Expression
+	ValueType   types.Type
+}
+
+func (ifExpression *IfExpression) Token() *token.Token {
+	return ifExpression.IfToken
+}
+
+func (ifExpression *IfExpression) ToString() string {
+	return "if " + ifExpression.Condition.ToString() + " " + ifExpression.Consequence.ToString() + " else " +
+		ifExpression.Alternative.ToString()
+}
+
 type IfStatement struct {
 	IfToken            *token.Token
 	Condition          Expression
@@ -321,11 +395,37 @@ func (ifStatement *IfStatement) ToString() string {
 	return result
 }
 
+type BreakStatement struct {
+	BreakToken *token.Token
+}
+
+func (breakStatement *BreakStatement) Token() *token.Token {
+	return breakStatement.BreakToken
+}
+
+func (breakStatement *BreakStatement) ToString() string {
+	return "break;"
+}
+
+type ContinueStatement struct {
+	ContinueToken *token.Token
+}
+
+func (continueStatement *ContinueStatement) Token() *token.Token {
+	return continueStatement.ContinueToken
+}
+
+func (continueStatement *ContinueStatement) ToString() string {
+	return "continue;"
+}
+
 type WhileStatement struct {
-	WhileToken       *token.Token
-	Condition        Expression
-	Statement        Statement
-	StatementContext *types.Context
+	WhileToken         *token.Token
+	Condition          Expression
+	Statement          Statement
+	StatementContext   *types.Context
+	Alternative        Statement
+	AlternativeContext *types.Context
 }
 
 func (whileStatement *WhileStatement) Token() *token.Token {
@@ -333,7 +433,11 @@ func (whileStatement *WhileStatement) Token() *token.Token {
 }
 
 func (whileStatement *WhileStatement) ToString() string {
-	return "while " + whileStatement.Condition.ToString() + " " + whileStatement.Statement.ToString()
+	result := "while " + whileStatement.Condition.ToString() + " " + whileStatement.Statement.ToString()
+	if whileStatement.Alternative != nil {
+		result += " else " + whileStatement.Alternative.ToString()
+	}
+	return result
 }
 
 type IncrementExpression struct {
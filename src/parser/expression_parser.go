@@ -18,27 +18,46 @@ const (
 	ExpressionSum
 	ExpressionProduct
 	ExpressionPrefix
+	ExpressionPower
 	ExpressionPostfix
 )
 
 var expressionPrecedences = map[token.Type]ExpressionPrecedence{
-	token.Assign:     ExpressionAssignment,
-	token.LogicalOr:  ExpressionLogicalOr,
-	token.LogicalAnd: ExpressionLogicalAnd,
-	token.EQ:         ExpressionEquals,
-	token.NEQ:        ExpressionEquals,
-	token.LT:         ExpressionRelation,
-	token.GT:         ExpressionRelation,
-	token.LTE:        ExpressionRelation,
-	token.GTE:        ExpressionRelation,
-	token.Plus:       ExpressionSum,
-	token.Minus:      ExpressionSum,
-	token.Slash:      ExpressionProduct,
-	token.Star:       ExpressionProduct,
-	token.Increment:  ExpressionPostfix,
-	token.Decrement:  ExpressionPostfix,
-	token.LParen:     ExpressionPostfix,
-	token.Dot:        ExpressionPostfix,
+	token.Assign:        ExpressionAssignment,
+	token.PlusAssign:    ExpressionAssignment,
+	token.MinusAssign:   ExpressionAssignment,
+	token.StarAssign:    ExpressionAssignment,
+	token.SlashAssign:   ExpressionAssignment,
+	token.PercentAssign: ExpressionAssignment,
+	token.PowerAssign:   ExpressionAssignment,
+	token.LogicalOr:     ExpressionLogicalOr,
+	token.LogicalAnd:    ExpressionLogicalAnd,
+	token.EQ:            ExpressionEquals,
+	token.NEQ:           ExpressionEquals,
+	token.LT:            ExpressionRelation,
+	token.GT:            ExpressionRelation,
+	token.LTE:           ExpressionRelation,
+	token.GTE:           ExpressionRelation,
+	token.In:            ExpressionRelation,
+	token.Plus:          ExpressionSum,
+	token.Minus:         ExpressionSum,
+	token.Slash:         ExpressionProduct,
+	token.Star:          ExpressionProduct,
+	token.Percent:       ExpressionProduct,
+	token.Power:         ExpressionPower,
+	token.Increment:     ExpressionPostfix,
+	token.Decrement:     ExpressionPostfix,
+	token.LParen:        ExpressionPostfix,
+	token.Dot:           ExpressionPostfix,
+}
+
+var compoundAssignmentOperators = map[token.Type]token.Type{
+	token.PlusAssign:    token.Plus,
+	token.MinusAssign:   token.Minus,
+	token.StarAssign:    token.Star,
+	token.SlashAssign:   token.Slash,
+	token.PercentAssign: token.Percent,
+	token.PowerAssign:   token.Power,
 }
 
 var prefixExpressionParseFunctions = make(map[token.Type]func(*types.Context) Expression)
@@ -62,11 +81,20 @@ func (parser *Parser) registerExpressionParseFunctions() {
 	prefixExpressionParseFunctions[token.False] = parser.parseBooleanLiteral
 	prefixExpressionParseFunctions[token.Bang] = parser.parsePrefixExpression
 	prefixExpressionParseFunctions[token.Minus] = parser.parsePrefixExpression
+	prefixExpressionParseFunctions[token.Plus] = parser.parsePrefixExpression
 	prefixExpressionParseFunctions[token.LParen] = parser.parseGroupedExpression
+	prefixExpressionParseFunctions[token.LBrace] = parser.parseBlockExpression
+	prefixExpressionParseFunctions[token.If] = parser.parseIfExpression
 	prefixExpressionParseFunctions[token.Increment] = parser.parseIncrementPrefixExpression
 	prefixExpressionParseFunctions[token.Decrement] = parser.parseIncrementPrefixExpression
 
 	infixExpressionParseFunctions[token.Assign] = parser.parseAssignmentExpression
+	infixExpressionParseFunctions[token.PlusAssign] = parser.parseAssignmentExpression
+	infixExpressionParseFunctions[token.MinusAssign] = parser.parseAssignmentExpression
+	infixExpressionParseFunctions[token.StarAssign] = parser.parseAssignmentExpression
+	infixExpressionParseFunctions[token.SlashAssign] = parser.parseAssignmentExpression
+	infixExpressionParseFunctions[token.PercentAssign] = parser.parseAssignmentExpression
+	infixExpressionParseFunctions[token.PowerAssign] = parser.parseAssignmentExpression
 	infixExpressionParseFunctions[token.LogicalOr] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.LogicalAnd] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.EQ] = parser.parseInfixExpression
@@ -75,10 +103,13 @@ func (parser *Parser) registerExpressionParseFunctions() {
 	infixExpressionParseFunctions[token.LT] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.GTE] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.LTE] = parser.parseInfixExpression
+	infixExpressionParseFunctions[token.In] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.Plus] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.Minus] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.Slash] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.Star] = parser.parseInfixExpression
+	infixExpressionParseFunctions[token.Percent] = parser.parseInfixExpression
+	infixExpressionParseFunctions[token.Power] = parser.parseInfixExpression
 	infixExpressionParseFunctions[token.LParen] = parser.parseCallExpression
 	infixExpressionParseFunctions[token.Increment] = parser.parseIncrementInfixExpression
 	infixExpressionParseFunctions[token.Decrement] = parser.parseIncrementInfixExpression
@@ -86,12 +117,18 @@ func (parser *Parser) registerExpressionParseFunctions() {
 }
 
 func (parser *Parser) parseExpression(context *types.Context, precedence ExpressionPrecedence) Expression {
+	if !parser.enter() {
+		parser.leave()
+		return &InvalidExpression{parser.current()}
+	}
+
 	currentToken := parser.current()
 	prefixFunction := prefixExpressionParseFunctions[currentToken.Type]
 	if prefixFunction == nil {
 		if currentToken.Type != token.Illegal {
 			parser.error(currentToken, "Unexpected %s", currentToken.ToString())
 		}
+		parser.leave()
 		return &InvalidExpression{currentToken}
 	}
 
@@ -99,7 +136,7 @@ func (parser *Parser) parseExpression(context *types.Context, precedence Express
 
 	for parser.peek().Type != token.Semi && precedence < getExpressionPrecedence(parser.peek()) {
 		infixFunction := infixExpressionParseFunctions[parser.peek().Type]
-		if infixFunction == nil {
+		if infixFunction == nil || parser.isPostfixOnNewLine() {
 			break
 		}
 
@@ -107,9 +144,16 @@ func (parser *Parser) parseExpression(context *types.Context, precedence Express
 		expression = infixFunction(context, expression)
 	}
 
+	parser.leave()
 	return expression
 }
 
+// a ++ or -- at the start of a line belongs to the next statement
+func (parser *Parser) isPostfixOnNewLine() bool {
+	next := parser.peek()
+	return (next.Type == token.Increment || next.Type == token.Decrement) && next.Line > parser.current().Line
+}
+
 /** prefix expressions **/
 
 func (parser *Parser) parsePrefixExpression(context *types.Context) Expression {
@@ -180,6 +224,96 @@ func (parser *Parser) parseGroupedExpression(context *types.Context) Expression
 	return expression
 }
 
+func (parser *Parser) parseBlockExpression(context *types.Context) Expression {
+	expressionContext := types.ExtendContext(context)
+	expressionContext.ReturnType = nil
+
+	loopDepth, blockValueEnd := parser.loopDepth, parser.blockValueEnd
+	parser.loopDepth = 0 // a block expression has to evaluate to a value
+	parser.blockValueEnd = nil
+	if end := parser.closingBrace(parser.position); end >= 0 {
+		parser.blockValueEnd = parser.tokens[end]
+	}
+	block := parser.parseBlockStatement(expressionContext)
+	parser.loopDepth, parser.blockValueEnd = loopDepth, blockValueEnd
+
+	parser.doesReturn(block.Context, block) // returning from a block expression is illegal
+
+	blockExpression := &BlockExpression{Block: block, Context: expressionContext, ValueType: &types.Void{}}
+	if len(block.Statements) > 0 {
+		lastStatement := block.Statements[len(block.Statements)-1]
+		// a trailing ';' discards the value, so '{ 1; }' is void unlike '{ 1 }'
+		if expressionStatement, isExpression := lastStatement.(*ExpressionStatement); isExpression && expressionStatement.SemiToken == nil {
+			blockExpression.ValueType = parser.getExpressionTypeSilently(expressionStatement.Expression, block.Context)
+		}
+	}
+	return blockExpression
+}
+
+// the 'if' of an 'else if' or 'elif' branch, it counts towards the nesting depth like any nested expression
+func (parser *Parser) parseNestedIfExpression(context *types.Context) Expression {
+	if !parser.enter() {
+		parser.leave()
+		return &InvalidExpression{parser.current()}
+	}
+	expression := parser.parseIfExpression(context)
+	parser.leave()
+	return expression
+}
+
+func (parser *Parser) parseIfExpression(context *types.Context) Expression {
+	ifExpression := &IfExpression{IfToken: parser.consume()}
+
+	ifExpression.Condition = parser.parseCondition(context)
+	if !parser.assertNext(token.LBrace) {
+		return &InvalidExpression{parser.current()}
+	}
+	ifExpression.Consequence = parser.parseBlockExpression(context).(*BlockExpression)
+
+	switch parser.peek().Type {
+	case token.Elif: // same as 'else if'
+		parser.consume()
+		ifExpression.Alternative = parser.parseNestedIfExpression(context)
+	case token.Else:
+		parser.consume()
+		// only the block or the nested 'if' belongs to the branch, like the consequence, so operators after it
+		// apply to the whole 'if' expression
+		switch parser.peek().Type {
+		case token.If:
+			parser.consume()
+			ifExpression.Alternative = parser.parseNestedIfExpression(context)
+		case token.LBrace:
+			parser.consume()
+			ifExpression.Alternative = parser.parseBlockExpression(context)
+		default:
+			parser.assertNext(token.LBrace)
+			return &InvalidExpression{parser.current()}
+		}
+	default:
+		parser.error(ifExpression.IfToken, "'if' expression is missing an 'else' branch")
+		return &InvalidExpression{ifExpression.IfToken}
+	}
+
+	consequenceType := ifExpression.Consequence.ValueType
+	alternativeType := parser.getExpressionTypeSilently(ifExpression.Alternative, context)
+	if isNever(consequenceType) || isNever(alternativeType) {
+		ifExpression.ValueType = &types.Never{}
+	} else if consequenceType.IsAssignable(alternativeType, context) {
+		ifExpression.ValueType = consequenceType
+	} else if alternativeType.IsAssignable(consequenceType, context) {
+		ifExpression.ValueType = alternativeType
+	} else if _, isNull := alternativeType.(*types.Null); isNull {
+		ifExpression.ValueType = &types.Optional{Base: consequenceType}
+	} else if _, isNull := consequenceType.(*types.Null); isNull {
+		ifExpression.ValueType = &types.Optional{Base: alternativeType}
+	} else {
+		parser.error(ifExpression.IfToken, "Type mismatch: branches of 'if' expression are '%s' and '%s'",
+			consequenceType.ToString(), alternativeType.ToString())
+		ifExpression.ValueType = &types.Never{}
+	}
+	return ifExpression
+}
+
 func (parser *Parser) parseIncrementPrefixExpression(context *types.Context) Expression {
 	operatorToken := parser.consume()
 	identExpression := parser.parseExpression(context, ExpressionPrefix)
@@ -191,6 +325,9 @@ func (parser *Parser) parseIncrementPrefixExpression(context *types.Context) Exp
 func (parser *Parser) parseInfixExpression(context *types.Context, left Expression) Expression {
 	currentToken := parser.consume()
 	precedence := expressionPrecedences[currentToken.Type]
+	if currentToken.Type == token.Power {
+		precedence-- // ** is right associative
+	}
 
 	right := parser.parseExpression(context, precedence)
 
@@ -204,7 +341,7 @@ func (parser *Parser) parseInfixExpression(context *types.Context, left Expressi
 
 func (parser *Parser) parseAssignmentExpression(context *types.Context, left Expression) Expression {
 	assignToken := parser.consume()
-	right := parser.parseExpression(context, ExpressionAssignment)
+	right := parser.parseExpression(context, ExpressionAssignment-1) // assignments are right associative
 
 	ident, isIdent := left.(*Identifier)
 	if !isIdent {
@@ -213,6 +350,16 @@ func (parser *Parser) parseAssignmentExpression(context *types.Context, left Exp
 		return &InvalidExpression{InvalidToken: assignToken}
 	}
 
+	// compound assignments like x += 1 are desugared to x = x + 1
+	if operator, isCompound := compoundAssignmentOperators[assignToken.Type]; isCompound {
+		right = &InfixExpression{
+			OperatorToken: assignToken,
+			Left:          ident,
+			Operator:      operator,
+			Right:         right,
+		}
+	}
+
 	return &AssignmentExpression{
 		IdentToken:  ident.IdentToken,
 		AssignToken: assignToken,
@@ -235,6 +382,10 @@ func (parser *Parser) parseCallExpression(context *types.Context, function Expre
 		}
 	}
 
+	if identifier, isIdent := function.(*Identifier); isIdent && identifier.Value == "assert" && len(argumentList) == 1 {
+		argumentList = parser.withAssertionSource(context, identifier, argumentList)
+	}
+
 	return &CallExpression{
 		ParenToken: currentToken,
 		Function:   function,
@@ -242,6 +393,19 @@ func (parser *Parser) parseCallExpression(context *types.Context, function Expre
 	}
 }
 
+// only the parser knows the source of an asserted condition, so it is passed as message if 'assert' accepts one
+func (parser *Parser) withAssertionSource(context *types.Context, identifier *Identifier, arguments []Expression) []Expression {
+	generic, isGeneric := parser.getExpressionType(identifier, context).(*types.Generic)
+	if !isGeneric {
+		return arguments
+	}
+	argumentType := parser.getExpressionType(arguments[0], context)
+	if _, err := generic.Resolve([]types.Type{argumentType, &types.String{}}, context); err != nil {
+		return arguments
+	}
+	return append(arguments, &StringLiteral{Value: arguments[0].ToString()})
+}
+
 func (parser *Parser) parseIncrementInfixExpression(_ *types.Context, identExpression Expression) Expression {
 	operatorToken := parser.current()
 	return parser.parseIncrementExpression(operatorToken, identExpression, false)
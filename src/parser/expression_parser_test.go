@@ -5,6 +5,7 @@ import (
 	"bananascript/src/token"
 	"bananascript/src/types"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/assert"
 	"testing"
 )
@@ -33,6 +34,42 @@ func TestExpressionParser(t *testing.T) {
 		},
 	)
 
+	assertExpression(t,
+		"-2 ** 3 ** 2 % 5",
+		&InfixExpression{
+			Left: &PrefixExpression{
+				Operator: token.Minus,
+				Expression: &InfixExpression{
+					Left:     &IntegerLiteral{Value: 2},
+					Operator: token.Power,
+					Right: &InfixExpression{
+						Left:     &IntegerLiteral{Value: 3},
+						Operator: token.Power,
+						Right:    &IntegerLiteral{Value: 2},
+					},
+				},
+			},
+			Operator: token.Percent,
+			Right:    &IntegerLiteral{Value: 5},
+		},
+	)
+
+	assertExpression(t,
+		"x %= 2 + 1",
+		&AssignmentExpression{
+			Name: &Identifier{Value: "x"},
+			Expression: &InfixExpression{
+				Left:     &Identifier{Value: "x"},
+				Operator: token.Percent,
+				Right: &InfixExpression{
+					Left:     &IntegerLiteral{Value: 2},
+					Operator: token.Plus,
+					Right:    &IntegerLiteral{Value: 1},
+				},
+			},
+		},
+	)
+
 	assertExpression(t,
 		"func(++x, !(a || b))",
 		&CallExpression{
@@ -65,7 +102,22 @@ func TestExpressionParser(t *testing.T) {
 
 	assertExpression(t,
 		"+2",
-		&InvalidExpression{},
+		&PrefixExpression{
+			Operator:   token.Plus,
+			Expression: &IntegerLiteral{Value: 2},
+		},
+	)
+
+	assertExpression(t,
+		"a + +b",
+		&InfixExpression{
+			Left:     &Identifier{Value: "a"},
+			Operator: token.Plus,
+			Right: &PrefixExpression{
+				Operator:   token.Plus,
+				Expression: &Identifier{Value: "b"},
+			},
+		},
 	)
 
 	assertExpression(t,
@@ -74,6 +126,76 @@ func TestExpressionParser(t *testing.T) {
 	)
 }
 
+func TestPrecedence(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"a || b && c == d + e * f ** g", "(a || (b && (c == (d + (e * (f ** g))))))"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"-a ** b", "(-(a ** b))"},
+		{"a * -b ** c", "(a * (-(b ** c)))"},
+		{"a + b ** -c * d", "(a + ((b ** (-c)) * d))"},
+		{"-a * b", "((-a) * b)"},
+		{"!-a", "(!(-a))"},
+		{"!a == b", "((!a) == b)"},
+		{"!a && b || c", "(((!a) && b) || c)"},
+		{"a < b == c < d", "((a < b) == (c < d))"},
+		{"a == b != c", "((a == b) != c)"},
+		{"a + b < c * d", "((a + b) < (c * d))"},
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b * c % d", "(((a / b) * c) % d)"},
+		{"a || b || c", "((a || b) || c)"},
+		{"a && b && c", "((a && b) && c)"},
+		{"a in b == c", "((a in b) == c)"},
+		{"a = b || c && d", "(a = (b || (c && d)))"},
+		{"(a + b) * c", "((a + b) * c)"},
+	}
+
+	for _, c := range cases {
+		expression := New(lexer.FromCode(c.input)).parseExpression(types.NewContext(), ExpressionLowest)
+		assert.Equal(t, expression.ToString(), c.expected, c.input)
+	}
+
+	// operators after an 'if' expression apply to all of it, not just to the 'else' branch
+	for _, input := range []string{"if c {1} else {2} + 3", "if c {1} else if d {2} else {3} + 3", "if c {1} elif d {2} else {3} + 3"} {
+		expression := New(lexer.FromCode(input)).parseExpression(types.NewContext(), ExpressionLowest)
+		infixExpression, isInfix := expression.(*InfixExpression)
+		assert.Assert(t, isInfix, input)
+		_, isIf := infixExpression.Left.(*IfExpression)
+		assert.Assert(t, isIf, input)
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	context := types.NewContext()
+	context.DefineMemberType("foo", &types.Int{})
+
+	for _, input := range []string{"1 + 2", "1 + 2;"} {
+		expression, errors := New(lexer.FromCode(input)).ParseExpression(context)
+		assert.Equal(t, len(errors), 0, input)
+		assert.DeepEqual(t, expression, &InfixExpression{
+			Left:     &IntegerLiteral{Value: 1},
+			Operator: token.Plus,
+			Right:    &IntegerLiteral{Value: 2},
+		}, cmp.Comparer(func(t1, t2 *token.Token) bool { return true }))
+	}
+
+	_, errors := New(lexer.FromCode("foo * 2")).ParseExpression(context)
+	assert.Equal(t, len(errors), 0)
+
+	for input, message := range map[string]string{
+		"1 + 2;foo":     "Unexpected identifier after expression",
+		"1 + 2 3":       "Unexpected integer literal after expression",
+		"1 + \"a\" - 2": "Type mismatch: string - int",
+		"bar":           "Cannot resolve reference to 'bar'",
+	} {
+		_, errors := New(lexer.FromCode(input)).ParseExpression(context)
+		assert.Equal(t, len(errors), 1, input)
+		assert.Equal(t, errors[0].Message, message)
+	}
+}
+
 func assertExpression(t *testing.T, input string, expected Expression) {
 
 	theLexer := lexer.FromCode(input)
@@ -86,5 +208,5 @@ func assertExpression(t *testing.T, input string, expected Expression) {
 		return true
 	})
 
-	assert.DeepEqual(t, expression, expected, ignoreTokens)
+	assert.DeepEqual(t, expression, expected, ignoreTokens, cmpopts.IgnoreUnexported(Identifier{}, CallExpression{}))
 }
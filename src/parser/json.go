@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"bananascript/src/types"
+	"encoding/json"
+)
+
+type jsonNode map[string]interface{}
+
+func ToJSON(program *Program) ([]byte, error) {
+	return json.Marshal(nodeToJSON(program))
+}
+
+func nodeToJSON(node Node) jsonNode {
+	if node == nil {
+		return nil
+	}
+
+	result := jsonNode{}
+	if nodeToken := node.Token(); nodeToken != nil {
+		result["line"] = nodeToken.Line
+		result["col"] = nodeToken.Col
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		result["type"] = "Program"
+		result["statements"] = nodesToJSON(node.Statements)
+	case *InvalidExpression:
+		result["type"] = "InvalidExpression"
+	case *Identifier:
+		result["type"] = "Identifier"
+		result["value"] = node.Value
+	case *EmptyStatement:
+		result["type"] = "EmptyStatement"
+	case *ExpressionStatement:
+		result["type"] = "ExpressionStatement"
+		result["expression"] = nodeToJSON(node.Expression)
+	case *PrefixExpression:
+		result["type"] = "PrefixExpression"
+		result["operator"] = node.Operator.ToString()
+		result["expression"] = nodeToJSON(node.Expression)
+	case *InfixExpression:
+		result["type"] = "InfixExpression"
+		result["operator"] = node.Operator.OperatorString()
+		result["left"] = nodeToJSON(node.Left)
+		result["right"] = nodeToJSON(node.Right)
+	case *AssignmentExpression:
+		result["type"] = "AssignmentExpression"
+		result["name"] = nodeToJSON(node.Name)
+		result["expression"] = nodeToJSON(node.Expression)
+	case *CallExpression:
+		result["type"] = "CallExpression"
+		result["function"] = nodeToJSON(node.Function)
+		result["arguments"] = nodesToJSON(node.Arguments)
+	case *StringLiteral:
+		result["type"] = "StringLiteral"
+		result["value"] = node.Value
+	case *IntegerLiteral:
+		result["type"] = "IntegerLiteral"
+		result["value"] = node.Value
+	case *FloatLiteral:
+		result["type"] = "FloatLiteral"
+		result["value"] = node.Value
+	case *BooleanLiteral:
+		result["type"] = "BooleanLiteral"
+		result["value"] = node.Value
+	case *NullLiteral:
+		result["type"] = "NullLiteral"
+	case *VoidLiteral:
+		result["type"] = "VoidLiteral"
+	case *FunctionDefinitionStatement:
+		result["type"] = "FunctionDefinitionStatement"
+		result["name"] = nodeToJSON(node.Name)
+		parameters := make([]jsonNode, 0)
+		for _, parameter := range node.Parameters {
+			parameters = append(parameters, parameterToJSON(parameter))
+		}
+		result["parameters"] = parameters
+		result["thisType"] = typeToJSON(node.ThisType)
+		result["thisName"] = nil
+		if node.ThisName != nil {
+			result["thisName"] = nodeToJSON(node.ThisName)
+		}
+		result["returnType"] = typeToJSON(node.ReturnType)
+		result["body"] = nodeToJSON(node.Body)
+		result["annotations"] = nodesToJSON(node.Annotations)
+	case *LetStatement:
+		result["type"] = "LetStatement"
+		result["name"] = nodeToJSON(node.Name)
+		result["valueType"] = typeToJSON(node.Type)
+		result["value"] = nodeToJSON(node.Value)
+		result["annotations"] = nodesToJSON(node.Annotations)
+	case *MultiLetStatement:
+		result["type"] = "MultiLetStatement"
+		declarations := make([]jsonNode, 0)
+		for _, declaration := range node.Declarations {
+			declarations = append(declarations, nodeToJSON(declaration))
+		}
+		result["declarations"] = declarations
+	case *ReturnStatement:
+		result["type"] = "ReturnStatement"
+		result["expression"] = nodeToJSON(node.Expression)
+	case *BlockStatement:
+		result["type"] = "BlockStatement"
+		result["statements"] = nodesToJSON(node.Statements)
+	case *BlockExpression:
+		result["type"] = "BlockExpression"
+		result["statements"] = nodesToJSON(node.Block.Statements)
+		result["valueType"] = typeToJSON(node.ValueType)
+	case *IfExpression:
+		result["type"] = "IfExpression"
+		result["condition"] = nodeToJSON(node.Condition)
+		result["consequence"] = nodeToJSON(node.Consequence)
+		result["alternative"] = nodeToJSON(node.Alternative)
+		result["valueType"] = typeToJSON(node.ValueType)
+	case *IfStatement:
+		result["type"] = "IfStatement"
+		result["condition"] = nodeToJSON(node.Condition)
+		result["statement"] = nodeToJSON(node.Statement)
+		result["alternative"] = nodeToJSON(node.Alternative)
+	case *WhileStatement:
+		result["type"] = "WhileStatement"
+		result["condition"] = nodeToJSON(node.Condition)
+		result["statement"] = nodeToJSON(node.Statement)
+		result["alternative"] = nodeToJSON(node.Alternative)
+	case *BreakStatement:
+		result["type"] = "BreakStatement"
+	case *ContinueStatement:
+		result["type"] = "ContinueStatement"
+	case *IncrementExpression:
+		result["type"] = "IncrementExpression"
+		result["operator"] = node.Operator.ToString()
+		result["name"] = nodeToJSON(node.Name)
+		result["pre"] = node.Pre
+	case *MemberAccessExpression:
+		result["type"] = "MemberAccessExpression"
+		result["expression"] = nodeToJSON(node.Expression)
+		result["member"] = nodeToJSON(node.Member)
+	case *TypeDefinitionStatement:
+		result["type"] = "TypeDefinitionStatement"
+		result["name"] = nodeToJSON(node.Name)
+		result["definedType"] = typeToJSON(node.Type)
+	}
+
+	return result
+}
+
+func nodesToJSON[T Node](nodes []T) []jsonNode {
+	result := make([]jsonNode, 0)
+	for _, node := range nodes {
+		result = append(result, nodeToJSON(node))
+	}
+	return result
+}
+
+func parameterToJSON(parameter *Parameter) jsonNode {
+	return jsonNode{
+		"type":      "Parameter",
+		"line":      parameter.Token.Line,
+		"col":       parameter.Token.Col,
+		"name":      nodeToJSON(parameter.Name),
+		"valueType": typeToJSON(parameter.Type),
+	}
+}
+
+func typeToJSON(theType types.Type) interface{} {
+	if theType == nil {
+		return nil
+	}
+	return theType.ToString()
+}
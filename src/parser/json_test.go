@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"bananascript/src/lexer"
+	"bananascript/src/types"
+	"encoding/json"
+	"gotest.tools/assert"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+
+	theLexer := lexer.FromCode("let a := 5;\nprintln(a + 1);")
+	theParser := New(theLexer)
+
+	context := types.NewContext()
+	context.DefineMemberType("println", &types.Function{
+		ParameterTypes: []types.Type{&types.Int{}},
+		ReturnType:     &types.Void{},
+	})
+
+	program, errors := theParser.ParseProgram(context)
+	assert.Assert(t, len(errors) == 0)
+
+	bytes, err := ToJSON(program)
+	assert.NilError(t, err)
+
+	var result map[string]interface{}
+	assert.NilError(t, json.Unmarshal(bytes, &result))
+	assert.Equal(t, result["type"], "Program")
+
+	statements := result["statements"].([]interface{})
+	assert.Equal(t, len(statements), 2)
+
+	letStatement := statements[0].(map[string]interface{})
+	assert.Equal(t, letStatement["type"], "LetStatement")
+	assert.Equal(t, letStatement["valueType"], "int")
+	assert.Equal(t, letStatement["line"], 1.)
+	assert.Equal(t, letStatement["col"], 1.)
+	assert.Equal(t, letStatement["name"].(map[string]interface{})["value"], "a")
+	assert.Equal(t, letStatement["value"].(map[string]interface{})["value"], 5.)
+
+	expressionStatement := statements[1].(map[string]interface{})
+	assert.Equal(t, expressionStatement["type"], "ExpressionStatement")
+	assert.Equal(t, expressionStatement["line"], 2.)
+
+	callExpression := expressionStatement["expression"].(map[string]interface{})
+	assert.Equal(t, callExpression["type"], "CallExpression")
+	assert.Equal(t, callExpression["col"], 8.)
+
+	argument := callExpression["arguments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, argument["type"], "InfixExpression")
+	assert.Equal(t, argument["operator"], "+")
+}
+
+func TestReceiverJSON(t *testing.T) {
+
+	theParser := New(lexer.FromCode("fn (int)::a() {}\nfn (self: int)::b() {}"))
+	program, errors := theParser.ParseProgram(types.NewContext())
+	assert.Assert(t, len(errors) == 0)
+
+	bytes, err := ToJSON(program)
+	assert.NilError(t, err)
+
+	var result map[string]interface{}
+	assert.NilError(t, json.Unmarshal(bytes, &result))
+
+	statements := result["statements"].([]interface{})
+	assert.Equal(t, statements[0].(map[string]interface{})["thisName"], nil)
+	assert.Equal(t, statements[1].(map[string]interface{})["thisName"].(map[string]interface{})["value"], "self")
+	assert.Equal(t, statements[1].(map[string]interface{})["thisType"], "int")
+}
@@ -0,0 +1,275 @@
+package parser
+
+import (
+	"bananascript/src/token"
+	"math"
+)
+
+// divisions by zero are not folded so that they still fail at runtime
+func FoldConstants(program *Program) {
+	for i, statement := range program.Statements {
+		program.Statements[i] = foldStatement(statement)
+	}
+}
+
+func foldStatement(statement Statement) Statement {
+	switch statement := statement.(type) {
+	case *ExpressionStatement:
+		statement.Expression = foldExpression(statement.Expression)
+	case *LetStatement:
+		statement.Value = foldExpression(statement.Value)
+	case *MultiLetStatement:
+		for _, declaration := range statement.Declarations {
+			foldStatement(declaration)
+		}
+	case *ReturnStatement:
+		statement.Expression = foldExpression(statement.Expression)
+	case *FunctionDefinitionStatement:
+		foldStatement(statement.Body)
+	case *BlockStatement:
+		for i, blockStatement := range statement.Statements {
+			statement.Statements[i] = foldStatement(blockStatement)
+		}
+	case *IfStatement:
+		statement.Condition = foldExpression(statement.Condition)
+		statement.Statement = foldStatement(statement.Statement)
+		if statement.Alternative != nil {
+			statement.Alternative = foldStatement(statement.Alternative)
+		}
+	case *WhileStatement:
+		statement.Condition = foldExpression(statement.Condition)
+		statement.Statement = foldStatement(statement.Statement)
+		if statement.Alternative != nil {
+			statement.Alternative = foldStatement(statement.Alternative)
+		}
+	}
+	return statement
+}
+
+func foldExpression(expression Expression) Expression {
+	switch expression := expression.(type) {
+	case *PrefixExpression:
+		expression.Expression = foldExpression(expression.Expression)
+		if folded := foldPrefixExpression(expression); folded != nil {
+			return folded
+		}
+	case *InfixExpression:
+		expression.Left = foldExpression(expression.Left)
+		expression.Right = foldExpression(expression.Right)
+		if folded := foldInfixExpression(expression); folded != nil {
+			return folded
+		}
+	case *AssignmentExpression:
+		expression.Expression = foldExpression(expression.Expression)
+	case *CallExpression:
+		expression.Function = foldExpression(expression.Function)
+		for i, argument := range expression.Arguments {
+			expression.Arguments[i] = foldExpression(argument)
+		}
+	case *MemberAccessExpression:
+		expression.Expression = foldExpression(expression.Expression)
+	case *BlockExpression:
+		foldStatement(expression.Block)
+	case *IfExpression:
+		expression.Condition = foldExpression(expression.Condition)
+		foldStatement(expression.Consequence.Block)
+		expression.Alternative = foldExpression(expression.Alternative)
+	}
+	return expression
+}
+
+func foldPrefixExpression(prefixExpression *PrefixExpression) Expression {
+	prefixToken := prefixExpression.PrefixToken
+	switch prefixExpression.Operator {
+	case token.Bang:
+		if value, ok := literalToBool(prefixExpression.Expression); ok {
+			return &BooleanLiteral{LiteralToken: prefixToken, Value: !value}
+		}
+	case token.Minus:
+		switch literal := prefixExpression.Expression.(type) {
+		case *IntegerLiteral:
+			return &IntegerLiteral{LiteralToken: prefixToken, Value: -literal.Value}
+		case *FloatLiteral:
+			return &FloatLiteral{LiteralToken: prefixToken, Value: -literal.Value}
+		}
+	case token.Plus:
+		switch literal := prefixExpression.Expression.(type) {
+		case *IntegerLiteral:
+			return &IntegerLiteral{LiteralToken: prefixToken, Value: literal.Value}
+		case *FloatLiteral:
+			return &FloatLiteral{LiteralToken: prefixToken, Value: literal.Value}
+		}
+	}
+	return nil
+}
+
+func foldInfixExpression(infixExpression *InfixExpression) Expression {
+	left, right := infixExpression.Left, infixExpression.Right
+	literalToken := left.Token()
+
+	switch infixExpression.Operator {
+	case token.LogicalAnd, token.LogicalOr:
+		leftValue, leftOk := literalToBool(left)
+		rightValue, rightOk := literalToBool(right)
+		if !leftOk || !rightOk {
+			return nil
+		}
+		if infixExpression.Operator == token.LogicalAnd {
+			return &BooleanLiteral{LiteralToken: literalToken, Value: leftValue && rightValue}
+		}
+		return &BooleanLiteral{LiteralToken: literalToken, Value: leftValue || rightValue}
+	case token.EQ, token.NEQ:
+		equals, ok := literalEquals(left, right)
+		if !ok {
+			return nil
+		}
+		if infixExpression.Operator == token.NEQ {
+			equals = !equals
+		}
+		return &BooleanLiteral{LiteralToken: literalToken, Value: equals}
+	case token.Plus:
+		_, leftIsString := left.(*StringLiteral)
+		_, rightIsString := right.(*StringLiteral)
+		if leftIsString || rightIsString {
+			leftString, leftOk := literalToString(left)
+			rightString, rightOk := literalToString(right)
+			if !leftOk || !rightOk {
+				return nil
+			}
+			return &StringLiteral{LiteralToken: literalToken, Value: leftString + rightString}
+		}
+	}
+
+	leftInt, leftIsInt := left.(*IntegerLiteral)
+	rightInt, rightIsInt := right.(*IntegerLiteral)
+	if leftIsInt && rightIsInt {
+		return foldIntegerInfix(infixExpression.Operator, leftInt.Value, rightInt.Value, literalToken)
+	}
+
+	leftFloat, leftOk := literalToFloat(left)
+	rightFloat, rightOk := literalToFloat(right)
+	if leftOk && rightOk {
+		return foldFloatInfix(infixExpression.Operator, leftFloat, rightFloat, literalToken)
+	}
+	return nil
+}
+
+func foldIntegerInfix(operator token.Type, left int64, right int64, literalToken *token.Token) Expression {
+	switch operator {
+	case token.LT:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left < right}
+	case token.GT:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left > right}
+	case token.LTE:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left <= right}
+	case token.GTE:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left >= right}
+	case token.Plus:
+		return &IntegerLiteral{LiteralToken: literalToken, Value: left + right}
+	case token.Minus:
+		return &IntegerLiteral{LiteralToken: literalToken, Value: left - right}
+	case token.Star:
+		return &IntegerLiteral{LiteralToken: literalToken, Value: left * right}
+	case token.Slash:
+		if right == 0 {
+			return nil
+		}
+		return &IntegerLiteral{LiteralToken: literalToken, Value: left / right}
+	case token.Percent:
+		if right == 0 {
+			return nil
+		}
+		return &IntegerLiteral{LiteralToken: literalToken, Value: left % right}
+	}
+	return nil
+}
+
+func foldFloatInfix(operator token.Type, left float64, right float64, literalToken *token.Token) Expression {
+	switch operator {
+	case token.LT:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left < right}
+	case token.GT:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left > right}
+	case token.LTE:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left <= right}
+	case token.GTE:
+		return &BooleanLiteral{LiteralToken: literalToken, Value: left >= right}
+	case token.Plus:
+		return &FloatLiteral{LiteralToken: literalToken, Value: left + right}
+	case token.Minus:
+		return &FloatLiteral{LiteralToken: literalToken, Value: left - right}
+	case token.Star:
+		return &FloatLiteral{LiteralToken: literalToken, Value: left * right}
+	case token.Slash:
+		return &FloatLiteral{LiteralToken: literalToken, Value: left / right}
+	case token.Percent:
+		return &FloatLiteral{LiteralToken: literalToken, Value: math.Mod(left, right)}
+	case token.Power:
+		result := math.Pow(left, right)
+		if isFinite(left) && isFinite(right) && !isFinite(result) {
+			return nil // left to the evaluator, which reports the error
+		}
+		return &FloatLiteral{LiteralToken: literalToken, Value: result}
+	}
+	return nil
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+func literalToBool(expression Expression) (bool, bool) {
+	switch literal := expression.(type) {
+	case *BooleanLiteral:
+		return literal.Value, true
+	case *IntegerLiteral:
+		return literal.Value != 0, true
+	case *FloatLiteral:
+		return literal.Value != 0, true
+	case *StringLiteral:
+		return len(literal.Value) != 0, true
+	}
+	return false, false
+}
+
+func literalToFloat(expression Expression) (float64, bool) {
+	switch literal := expression.(type) {
+	case *IntegerLiteral:
+		return float64(literal.Value), true
+	case *FloatLiteral:
+		return literal.Value, true
+	}
+	return math.NaN(), false
+}
+
+func literalToString(expression Expression) (string, bool) {
+	switch literal := expression.(type) {
+	case *StringLiteral:
+		return literal.Value, true
+	case *IntegerLiteral, *FloatLiteral, *BooleanLiteral:
+		return literal.ToString(), true
+	}
+	return "", false
+}
+
+func literalEquals(left Expression, right Expression) (bool, bool) {
+	switch left := left.(type) {
+	case *IntegerLiteral:
+		if right, ok := right.(*IntegerLiteral); ok {
+			return left.Value == right.Value, true
+		}
+	case *FloatLiteral:
+		if right, ok := right.(*FloatLiteral); ok {
+			return left.Value == right.Value, true
+		}
+	case *StringLiteral:
+		if right, ok := right.(*StringLiteral); ok {
+			return left.Value == right.Value, true
+		}
+	case *BooleanLiteral:
+		if right, ok := right.(*BooleanLiteral); ok {
+			return left.Value == right.Value, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"bananascript/src/lexer"
+	"bananascript/src/token"
+	"bananascript/src/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gotest.tools/assert"
+	"testing"
+)
+
+func TestFoldConstants(t *testing.T) {
+
+	assertFolded(t,
+		"2 + 3 * 4;",
+		&IntegerLiteral{Value: 14},
+	)
+
+	assertFolded(t,
+		"-(1.5 * 2) + 1;",
+		&FloatLiteral{Value: -2},
+	)
+
+	assertFolded(t,
+		"\"a\" + 1 + true;",
+		&StringLiteral{Value: "a1true"},
+	)
+
+	assertFolded(t,
+		"!(1 < 2) || 3 == 3;",
+		&BooleanLiteral{Value: true},
+	)
+
+	assertFolded(t,
+		"1 / 0;",
+		&InfixExpression{
+			Left:     &IntegerLiteral{Value: 1},
+			Operator: token.Slash,
+			Right:    &IntegerLiteral{Value: 0},
+		},
+	)
+
+	assertFolded(t,
+		"a + 2 * 3;",
+		&InfixExpression{
+			Left:     &Identifier{Value: "a"},
+			Operator: token.Plus,
+			Right:    &IntegerLiteral{Value: 6},
+		},
+	)
+
+	assertFolded(t,
+		"a++ + 1;",
+		&InfixExpression{
+			Left: &IncrementExpression{
+				Operator: token.Increment,
+				Name:     &Identifier{Value: "a"},
+			},
+			Operator: token.Plus,
+			Right:    &IntegerLiteral{Value: 1},
+		},
+	)
+}
+
+func assertFolded(t *testing.T, input string, expected Expression) {
+
+	theLexer := lexer.FromCode(input)
+	theParser := New(theLexer)
+
+	context := types.NewContext()
+	context.DefineMemberType("a", &types.Int{})
+	program, errors := theParser.ParseProgram(context)
+	assert.Assert(t, len(errors) == 0, input)
+
+	FoldConstants(program)
+
+	ignoreTokens := cmp.Comparer(func(t1, t2 *token.Token) bool {
+		return true
+	})
+
+	statement := program.Statements[0].(*ExpressionStatement)
+	assert.DeepEqual(t, statement.Expression, expected, ignoreTokens, cmpopts.IgnoreUnexported(Identifier{}, CallExpression{}))
+}
@@ -8,10 +8,43 @@ import (
 	"reflect"
 )
 
+// statements and expressions nested deeper than this are rejected instead of overflowing the stack
+var MaxNestingDepth = 1000
+
 type Parser struct {
-	errors   []*errors.ParserError
-	tokens   []*token.Token
-	position int
+	errors        []*errors.ParserError
+	tokens        []*token.Token
+	position      int
+	hoisted       map[*token.Token]bool
+	initializing  map[string]int
+	functionDepth int
+	loopDepth     int
+	nestingDepth  int
+	tooDeep       bool
+	warnUnused    bool
+	strict        bool
+	declarations  []declaration
+	warnings      []*errors.ParserError
+	recovering    *token.Token // a statement failed at this token, errors at it are not reported again
+	blockValueEnd *token.Token // closes the innermost block expression, a block or 'if' right before it is its value
+	globalReads
+}
+
+// top-level functions can be used before their definition, so the top-level variables they read are checked
+// to be declared at every place they are used outside of a function
+type globalReads struct {
+	topLevelFunctions map[string]bool
+	topLevelFunction  string                   // the top-level function whose body is being parsed
+	functionReads     map[string][]*Identifier // top-level variables and functions read by each function
+	functionUses      []*Identifier            // top-level functions used outside of functions
+	declaredAt        map[string]*token.Token  // a top-level variable is declared after this token
+	referenced        map[*Identifier]bool
+}
+
+// a variable declared by let, checked for usage after parsing if unused warnings are enabled
+type declaration struct {
+	context    *types.Context
+	identifier *Identifier
 }
 
 func New(lexer *lexer.Lexer) *Parser {
@@ -24,21 +57,98 @@ func New(lexer *lexer.Lexer) *Parser {
 		}
 	}
 
-	parser := &Parser{tokens: tokens, errors: lexer.Errors}
+	parser := &Parser{tokens: tokens, errors: lexer.Errors, hoisted: make(map[*token.Token]bool),
+		initializing: make(map[string]int)}
+	parser.globalReads = globalReads{
+		topLevelFunctions: make(map[string]bool),
+		functionReads:     make(map[string][]*Identifier),
+		declaredAt:        make(map[string]*token.Token),
+		referenced:        make(map[*Identifier]bool),
+	}
 	parser.registerExpressionParseFunctions()
 	parser.registerTypeParseFunctions()
 	return parser
 }
 
+// enables warnings for variables that are declared but never read, they do not count as errors
+func (parser *Parser) WarnUnused() {
+	parser.warnUnused = true
+}
+
+// disables implicit bool conversions of the operands of && and ||, they have to be of type bool
+func (parser *Parser) Strict() {
+	parser.strict = true
+}
+
+func (parser *Parser) Warnings() []*errors.ParserError {
+	return parser.warnings
+}
+
+func (parser *Parser) checkUnused() {
+	if !parser.warnUnused {
+		return
+	}
+	for _, declaration := range parser.declarations {
+		if !declaration.context.IsUsed(declaration.identifier.Value) {
+			parser.warnings = append(parser.warnings, errors.NewFromToken(declaration.identifier.IdentToken,
+				"Unused variable '%s'", declaration.identifier.Value))
+		}
+	}
+}
+
 func (parser *Parser) error(token *token.Token, messageFormat string, args ...interface{}) {
+	if parser.tooDeep {
+		return // the rest of the input was skipped
+	}
+	if parser.recovering != nil && samePosition(parser.recovering, token) {
+		return
+	}
 	parser.errors = append(parser.errors, errors.NewFromToken(token, messageFormat, args...))
 }
 
+// parses a statement of a program or block. If the previous statement failed at the token this one starts at,
+// e.g. because it did not end before it, errors at that token are only consequences and not reported again
+func (parser *Parser) parseNextStatement(context *types.Context) Statement {
+	recovering := parser.recovering
+	parser.recovering = nil
+	if len(parser.errors) > 0 {
+		last := parser.errors[len(parser.errors)-1]
+		if last.Line == parser.current().Line && last.Col == parser.current().Col && last.File == parser.current().File {
+			parser.recovering = parser.current()
+		}
+	}
+	statement := parser.parseStatement(context)
+	parser.recovering = recovering
+	return statement
+}
+
+func samePosition(first *token.Token, second *token.Token) bool {
+	return first.Line == second.Line && first.Col == second.Col && first.File == second.File
+}
+
+// increases the nesting depth, once it exceeds the maximum the rest of the input is skipped
+func (parser *Parser) enter() bool {
+	parser.nestingDepth++
+	if parser.nestingDepth <= MaxNestingDepth {
+		return true
+	}
+	if !parser.tooDeep {
+		parser.error(parser.current(), "Maximum nesting depth exceeded")
+		parser.tooDeep = true
+		parser.position = len(parser.tokens) - 1
+	}
+	return false
+}
+
+func (parser *Parser) leave() {
+	parser.nestingDepth--
+}
+
 func (parser *Parser) current() *token.Token {
 	if parser.position < len(parser.tokens) {
 		return parser.tokens[parser.position]
 	} else {
-		return parser.tokens[parser.position-1]
+		return parser.tokens[len(parser.tokens)-1]
 	}
 }
 
@@ -56,6 +166,68 @@ func (parser *Parser) peek() *token.Token {
 	}
 }
 
+// index of the '}' closing the block opened at the given index, or -1 if it is not closed
+func (parser *Parser) closingBrace(index int) int {
+	depth := 0
+	for i := index; i < len(parser.tokens); i++ {
+		switch parser.tokens[i].Type {
+		case token.LBrace:
+			depth++
+		case token.RBrace:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// index of the last token of the block or 'if' chain starting at the given index.
+// Returns -1 for an 'if' without 'else', which has no value
+func (parser *Parser) valueStatementEnd(index int) int {
+	for parser.tokens[index].Type != token.LBrace {
+		// the branch of an 'if' starts at the first '{' that is not nested in parentheses
+		parentheses := 0
+		for index++; parentheses > 0 || parser.tokens[index].Type != token.LBrace; index++ {
+			switch parser.tokens[index].Type {
+			case token.LParen:
+				parentheses++
+			case token.RParen:
+				parentheses--
+			case token.EOF:
+				return -1
+			}
+		}
+		end := parser.closingBrace(index)
+		if end < 0 || end+2 >= len(parser.tokens) {
+			return -1
+		}
+		switch parser.tokens[end+1].Type {
+		case token.Elif:
+			index = end + 1
+		case token.Else:
+			index = end + 2
+			if parser.tokens[index].Type != token.If && parser.tokens[index].Type != token.LBrace {
+				return -1
+			}
+		default:
+			return -1
+		}
+	}
+	return parser.closingBrace(index)
+}
+
+// whether the block or 'if' at the current token is the last statement of a block expression, then it is
+// parsed as an expression so that it becomes the value of the block
+func (parser *Parser) isBlockValue() bool {
+	if parser.blockValueEnd == nil {
+		return false
+	}
+	end := parser.valueStatementEnd(parser.position)
+	return end >= 0 && end+1 < len(parser.tokens) && parser.tokens[end+1] == parser.blockValueEnd
+}
+
 func (parser *Parser) assertNext(tokenType token.Type) bool {
 	if nextToken := parser.peek(); nextToken.Type == tokenType {
 		parser.consume()
@@ -67,19 +239,46 @@ func (parser *Parser) assertNext(tokenType token.Type) bool {
 	}
 }
 
+// statements may omit their semicolon if the next token is on a new line or closes the block
+func (parser *Parser) isStatementEnd() bool {
+	next := parser.peek()
+	switch next.Type {
+	case token.Semi, token.RBrace, token.EOF:
+		return true
+	default:
+		return next.Line > parser.current().Line
+	}
+}
+
+func (parser *Parser) assertStatementEnd() bool {
+	if parser.peek().Type == token.Semi {
+		parser.consume()
+		return true
+	} else if parser.isStatementEnd() {
+		return true
+	}
+	return parser.assertNext(token.Semi)
+}
+
 func (parser *Parser) ParseProgram(context *types.Context) (*Program, []*errors.ParserError) {
 
 	program := &Program{}
 	program.Statements = []Statement{}
 	program.Context = types.ExtendContext(context)
+	program.Context.Global = true
+	parser.hoistTypeDefinitions(program.Context)
+	parser.hoistFunctionDefinitions(program.Context)
 
 	for parser.current().Type != token.EOF {
 		if parser.current().Type == token.Semi || parser.current().Type == token.Illegal {
 			parser.consume()
 			continue
+		} else if parser.current().Type == token.RBrace {
+			parser.error(parser.consume(), "Unexpected '}' without matching '{'")
+			continue
 		}
 
-		statement := parser.parseStatement(program.Context)
+		statement := parser.parseNextStatement(program.Context)
 
 		if statement != nil && !reflect.ValueOf(statement).IsNil() {
 			program.Statements = append(program.Statements, statement)
@@ -88,19 +287,191 @@ func (parser *Parser) ParseProgram(context *types.Context) (*Program, []*errors.
 	}
 
 	parser.doesReturn(context, program)
+	parser.checkGlobalReads()
+	parser.checkMainSignature(program)
+	parser.checkUnused()
 	return program, parser.errors
 }
 
+// parses input that consists of a single expression, e.g. a value in a config file
+func (parser *Parser) ParseExpression(context *types.Context) (Expression, []*errors.ParserError) {
+	expression := parser.parseExpression(context, ExpressionLowest)
+	parser.getExpressionType(expression, context) // check for errors
+
+	if !isInvalid(expression) {
+		if parser.peek().Type == token.Semi {
+			parser.consume()
+		}
+		if next := parser.peek(); next.Type != token.EOF {
+			parser.error(next, "Unexpected %s after expression", next.ToString())
+		}
+	}
+	return expression, parser.errors
+}
+
+// a top-level main function is run after all other statements, its result is used as exit code
+func (parser *Parser) checkMainSignature(program *Program) {
+	mainType, exists := program.Context.GetMemberTypeStrict("main")
+	if !exists {
+		return
+	}
+	if functionType, isFunction := mainType.(*types.Function); isFunction && len(functionType.ParameterTypes) == 0 {
+		switch functionType.ReturnType.(type) {
+		case *types.Int, *types.Void:
+			return
+		}
+	}
+
+	for _, statement := range program.Statements {
+		names := make([]*Identifier, 0)
+		switch statement := statement.(type) {
+		case *FunctionDefinitionStatement:
+			if statement.ThisType == nil {
+				names = append(names, statement.Name)
+			}
+		case *LetStatement:
+			names = append(names, statement.Name)
+		case *MultiLetStatement:
+			for _, declaration := range statement.Declarations {
+				names = append(names, declaration.Name)
+			}
+		}
+		for _, name := range names {
+			if name.Value == "main" {
+				parser.error(name.IdentToken, "'main' must be a function without parameters returning 'int' or 'void'")
+				return
+			}
+		}
+	}
+}
+
+func (parser *Parser) redefinitionError(context *types.Context, identifier *Identifier) {
+	if context.Global && context.IsBuiltin(identifier.Value) {
+		parser.error(identifier.IdentToken, "Cannot redefine builtin '%s'", identifier.Value)
+	} else {
+		parser.error(identifier.IdentToken, "Cannot redefine '%s'", identifier.Value)
+	}
+}
+
+// declares all top-level interfaces up front, so that they can refer to each other in any order
+func (parser *Parser) hoistTypeDefinitions(context *types.Context) {
+	depth := 0
+	for i, currentToken := range parser.tokens {
+		switch currentToken.Type {
+		case token.LBrace:
+			depth++
+		case token.RBrace:
+			depth--
+		case token.TypeDef:
+			if depth != 0 || (i > 0 && !isStatementStart(parser.tokens[i-1], currentToken)) || i+3 >= len(parser.tokens) {
+				continue
+			}
+			name, define, iface := parser.tokens[i+1], parser.tokens[i+2], parser.tokens[i+3]
+			if name.Type != token.Ident || define.Type != token.Define || iface.Type != token.Iface {
+				continue
+			}
+			if _, ok := context.DefineType(name.Literal, &types.Iface{Name: name.Literal}); ok {
+				parser.hoisted[currentToken] = true
+			}
+		}
+	}
+}
+
+// defines the signatures of all top-level functions up front, so that they can be referenced before their definition
+func (parser *Parser) hoistFunctionDefinitions(context *types.Context) {
+	depth := 0
+	for i, currentToken := range parser.tokens {
+		switch currentToken.Type {
+		case token.LBrace:
+			depth++
+		case token.RBrace:
+			depth--
+		case token.Func:
+			start := i
+			for start >= 2 && parser.tokens[start-1].Type == token.Ident && parser.tokens[start-2].Type == token.At {
+				start -= 2 // annotations
+			}
+			if depth != 0 || (start > 0 && !isStatementStart(parser.tokens[start-1], parser.tokens[start])) {
+				continue
+			}
+			parser.position = i
+			errorCount := len(parser.errors)
+			statement := &FunctionDefinitionStatement{FuncToken: currentToken}
+			if parser.parseFunctionSignature(context, statement) && len(parser.errors) == errorCount {
+				parser.hoisted[currentToken] = parser.defineFunction(context, statement)
+				if parser.hoisted[currentToken] && statement.ThisType == nil {
+					parser.topLevelFunctions[statement.Name.Value] = true
+				}
+			}
+			parser.errors = parser.errors[:errorCount] // reported again when parsing the definition
+		}
+	}
+	parser.position = 0
+}
+
+// records a reference to a top-level name, to check later that a function using it is not used too early
+func (parser *Parser) referenceGlobal(identifier *Identifier) {
+	if parser.referenced[identifier] {
+		return
+	}
+	parser.referenced[identifier] = true
+	if parser.topLevelFunction != "" {
+		parser.functionReads[parser.topLevelFunction] = append(parser.functionReads[parser.topLevelFunction], identifier)
+	} else if parser.topLevelFunctions[identifier.Value] {
+		parser.functionUses = append(parser.functionUses, identifier)
+	}
+}
+
+func (parser *Parser) checkGlobalReads() {
+	for _, use := range parser.functionUses {
+		if variable := parser.undeclaredRead(use.Value, use.IdentToken, make(map[string]bool)); variable != nil {
+			parser.error(use.IdentToken, "Cannot use '%s' here, it reads '%s' which is not declared yet",
+				use.Value, variable.Value)
+		}
+	}
+}
+
+// returns a top-level variable that is read by the function, directly or through other functions,
+// but only declared after the token where the function is used
+func (parser *Parser) undeclaredRead(function string, use *token.Token, visited map[string]bool) *Identifier {
+	if visited[function] {
+		return nil
+	}
+	visited[function] = true
+	for _, read := range parser.functionReads[function] {
+		if parser.topLevelFunctions[read.Value] {
+			if variable := parser.undeclaredRead(read.Value, use, visited); variable != nil {
+				return variable
+			}
+		} else if declaredAt, ok := parser.declaredAt[read.Value]; ok && !before(declaredAt, use) {
+			return read
+		}
+	}
+	return nil
+}
+
+func before(first *token.Token, second *token.Token) bool {
+	return first.Line < second.Line || (first.Line == second.Line && first.Col < second.Col)
+}
+
+func isStatementStart(previous *token.Token, current *token.Token) bool {
+	return previous.Type == token.Semi || previous.Type == token.RBrace || previous.Line < current.Line
+}
+
 func (parser *Parser) doesReturn(context *types.Context, statement Statement) bool {
 
 	switch statement := statement.(type) {
 	case *Program:
-		for _, statement := range statement.Statements {
-			parser.doesReturn(context, statement)
+		for _, programStatement := range statement.Statements {
+			parser.doesReturn(statement.Context, programStatement)
 		}
 	case *ReturnStatement:
 		if context.ReturnType != nil {
-			if !isNever(context.ReturnType) {
+			_, returnsVoid := context.ReturnType.(*types.Void)
+			_, isBare := statement.Expression.(*VoidLiteral)
+			if returnsVoid && !isBare {
+				parser.error(statement.ReturnToken, "Cannot return a value from a void function")
+			} else if !isNever(context.ReturnType) {
 				returnType := parser.getExpressionType(statement.Expression, context)
 				if !isNever(returnType) && !context.ReturnType.IsAssignable(returnType, context) {
 					parser.error(statement.ReturnToken, "Type '%s' is not assignable to '%s'", returnType.ToString(),
@@ -108,8 +479,10 @@ func (parser *Parser) doesReturn(context *types.Context, statement Statement) bo
 				}
 			}
 			return true
+		} else if parser.functionDepth > 0 {
+			parser.error(statement.ReturnToken, "Cannot return from a block expression")
 		} else {
-			parser.error(statement.ReturnToken, "Illegal return statement")
+			parser.error(statement.ReturnToken, "Cannot return outside of a function")
 		}
 	case *BlockStatement:
 		newContext := statement.Context
@@ -123,12 +496,31 @@ func (parser *Parser) doesReturn(context *types.Context, statement Statement) bo
 		}
 		return returned
 	case *IfStatement:
-		return parser.doesReturn(statement.StatementContext, statement.Statement) &&
-			parser.doesReturn(statement.AlternativeContext, statement.Alternative)
+		statementReturns := parser.doesReturn(statement.StatementContext, statement.Statement)
+		alternativeReturns := parser.doesReturn(statement.AlternativeContext, statement.Alternative)
+		return statementReturns && alternativeReturns
+	case *ExpressionStatement:
+		// calling a function that returns never, like exit, ends the function as well
+		if callExpression, isCall := statement.Expression.(*CallExpression); isCall {
+			return callExpression.functionType != nil && isNever(callExpression.functionType.ReturnType)
+		}
+	case *WhileStatement:
+		// the body might never run and the 'else' only runs without any iteration,
+		// so a loop never counts as returning
+		parser.doesReturn(statement.StatementContext, statement.Statement)
+		parser.doesReturn(statement.AlternativeContext, statement.Alternative)
 	}
 	return false
 }
 
+// for statements that have already been checked, so that errors are not reported twice
+func (parser *Parser) doesReturnSilently(context *types.Context, statement Statement) bool {
+	errorCount := len(parser.errors)
+	returns := parser.doesReturn(context, statement)
+	parser.errors = parser.errors[:errorCount]
+	return returns
+}
+
 func (parser *Parser) parseParameterList(context *types.Context) []*Parameter {
 
 	parameters := make([]*Parameter, 0)
@@ -137,11 +529,17 @@ func (parser *Parser) parseParameterList(context *types.Context) []*Parameter {
 		return parameters
 	}
 
+	names := make(map[string]bool)
 	for {
 		parameter := parser.parseParameter(context)
 		if parameter == nil {
 			return nil
 		}
+		if names[parameter.Name.Value] {
+			parser.error(parameter.Token, "Duplicate parameter '%s'", parameter.Name.Value)
+		} else if parameter.Name.Value != types.Discard {
+			names[parameter.Name.Value] = true
+		}
 		parameters = append(parameters, parameter)
 		if parser.peek().Type == token.Comma {
 			parser.consume()
@@ -156,6 +554,15 @@ func (parser *Parser) parseParameterList(context *types.Context) []*Parameter {
 	return parameters
 }
 
+func hasParameter(parameters []*Parameter, name string) bool {
+	for _, parameter := range parameters {
+		if parameter.Name.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (parser *Parser) parseParameter(context *types.Context) *Parameter {
 
 	if !parser.assertNext(token.Ident) {
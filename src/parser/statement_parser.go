@@ -7,6 +7,16 @@ import (
 )
 
 func (parser *Parser) parseStatement(context *types.Context) Statement {
+	if !parser.enter() {
+		parser.leave()
+		return nil
+	}
+	statement := parser.parseStatementOfType(context)
+	parser.leave()
+	return statement
+}
+
+func (parser *Parser) parseStatementOfType(context *types.Context) Statement {
 	switch parser.current().Type {
 	case token.Let:
 		return parser.parseLetStatement(context)
@@ -15,40 +25,102 @@ func (parser *Parser) parseStatement(context *types.Context) Statement {
 	case token.Func:
 		return parser.parseFunctionDefinitionStatement(context)
 	case token.LBrace:
+		if parser.isBlockValue() {
+			return parser.parseExpressionStatement(context)
+		}
 		return parser.parseBlockStatement(context)
 	case token.If:
+		if parser.isBlockValue() {
+			return parser.parseExpressionStatement(context)
+		}
 		return parser.parseIfStatement(context)
 	case token.While:
 		return parser.parseWhileStatement(context)
+	case token.Break:
+		return parser.parseBreakStatement()
+	case token.Continue:
+		return parser.parseContinueStatement()
 	case token.TypeDef:
 		return parser.parseTypeDefinitionStatement(context)
+	case token.At:
+		return parser.parseAnnotatedStatement(context)
+	case token.Semi:
+		return &EmptyStatement{SemiToken: parser.current()}
+	case token.Ident:
+		if parser.peek().Type == token.Define {
+			return parser.parseShortLetStatement(context)
+		}
+		return parser.parseExpressionStatement(context)
 	default:
 		return parser.parseExpressionStatement(context)
 	}
 }
 
+// annotations are metadata for host tooling, they do not change how the statement is evaluated
+func (parser *Parser) parseAnnotatedStatement(context *types.Context) Statement {
+	annotations := make([]*Identifier, 0)
+	for parser.current().Type == token.At {
+		if !parser.assertNext(token.Ident) {
+			return nil
+		}
+		annotations = append(annotations, &Identifier{IdentToken: parser.current(), Value: parser.current().Literal})
+		parser.consume()
+	}
+
+	switch parser.current().Type {
+	case token.Func:
+		statement := parser.parseFunctionDefinitionStatement(context)
+		if statement == nil {
+			return nil
+		}
+		statement.Annotations = annotations
+		return statement
+	case token.Let:
+		statement := parser.parseLetStatement(context)
+		switch statement := statement.(type) {
+		case *LetStatement:
+			if statement != nil {
+				statement.Annotations = annotations
+			}
+		case *MultiLetStatement:
+			for _, declaration := range statement.Declarations {
+				declaration.Annotations = annotations
+			}
+		}
+		return statement
+	default:
+		parser.error(parser.current(), "Annotations can only be used on 'fn' and 'let'")
+		return parser.parseStatementOfType(context)
+	}
+}
+
 func (parser *Parser) parseExpressionStatement(context *types.Context) *ExpressionStatement {
-	statement := &ExpressionStatement{}
+	statement := &ExpressionStatement{FirstToken: parser.current()}
 	statement.Expression = parser.parseExpression(context, ExpressionLowest)
 	parser.getExpressionType(statement.Expression, context) // check for errors
 
 	if !isInvalid(statement.Expression) {
-		parser.assertNext(token.Semi)
+		if parser.peek().Type == token.Semi {
+			statement.SemiToken = parser.peek()
+		}
+		parser.assertStatementEnd()
 	}
 
 	return statement
 }
 
 func (parser *Parser) parseReturnStatement(context *types.Context) *ReturnStatement {
-	statement := &ReturnStatement{ReturnToken: parser.consume()}
+	statement := &ReturnStatement{ReturnToken: parser.current()}
 
-	if parser.current().Type == token.Semi {
+	if parser.isStatementEnd() {
 		statement.Expression = &VoidLiteral{}
+		parser.assertStatementEnd()
 		return statement
 	}
 
+	parser.consume()
 	statement.Expression = parser.parseExpression(context, ExpressionLowest)
-	parser.assertNext(token.Semi)
+	parser.assertStatementEnd()
 	return statement
 }
 
@@ -64,7 +136,7 @@ func (parser *Parser) parseBlockStatement(context *types.Context) *BlockStatemen
 			continue
 		}
 
-		statement := parser.parseStatement(newContext)
+		statement := parser.parseNextStatement(newContext)
 		if statement != nil && !reflect.ValueOf(statement).IsNil() {
 			statements = append(statements, statement)
 		}
@@ -80,11 +152,38 @@ func (parser *Parser) parseBlockStatement(context *types.Context) *BlockStatemen
 	return &BlockStatement{Statements: statements, LBraceToken: openingBrace, RBraceToken: rBraceToken, Context: newContext}
 }
 
-func (parser *Parser) parseLetStatement(context *types.Context) *LetStatement {
-	statement := &LetStatement{LetToken: parser.current()}
+func (parser *Parser) parseLetStatement(context *types.Context) Statement {
+	letToken := parser.current()
 	if !parser.assertNext(token.Ident) {
 		return nil
 	}
+	statement := parser.parseLetDeclaration(context, &LetStatement{LetToken: letToken}, true)
+	if statement == nil || parser.peek().Type != token.Comma {
+		return statement
+	}
+
+	// every declaration is defined before the next one is parsed, so later ones can refer to earlier ones
+	multiLetStatement := &MultiLetStatement{LetToken: letToken, Declarations: []*LetStatement{statement}}
+	for parser.peek().Type == token.Comma {
+		parser.consume()
+		if !parser.assertNext(token.Ident) {
+			return nil
+		}
+		statement = parser.parseLetDeclaration(context, &LetStatement{LetToken: letToken}, true)
+		if statement == nil {
+			return nil
+		}
+		multiLetStatement.Declarations = append(multiLetStatement.Declarations, statement)
+	}
+	return multiLetStatement
+}
+
+func (parser *Parser) parseShortLetStatement(context *types.Context) *LetStatement {
+	statement := &LetStatement{LetToken: parser.current()}
+	return parser.parseLetDeclaration(context, statement, false)
+}
+
+func (parser *Parser) parseLetDeclaration(context *types.Context, statement *LetStatement, allowMultiple bool) *LetStatement {
 
 	identToken := parser.current()
 	name := identToken.Literal
@@ -98,8 +197,13 @@ func (parser *Parser) parseLetStatement(context *types.Context) *LetStatement {
 		assignmentToken = token.Assign
 	}
 
-	if parser.peek().Type != token.Semi {
+	// the name is not defined while its initializer is checked, so references resolve to outer bindings
+	parser.initializing[name]++
+	isMultiple := allowMultiple && parser.peek().Type == token.Comma
+	uninitialized := parser.isStatementEnd() || isMultiple
+	if !uninitialized {
 		if !parser.assertNext(assignmentToken) {
+			parser.initializing[name]--
 			return nil
 		}
 		parser.consume()
@@ -108,11 +212,16 @@ func (parser *Parser) parseLetStatement(context *types.Context) *LetStatement {
 		statement.Value = &NullLiteral{}
 	}
 
-	parser.assertNext(token.Semi)
+	if !allowMultiple || parser.peek().Type != token.Comma {
+		parser.assertStatementEnd()
+	}
 
 	inferredType := parser.getExpressionType(statement.Value, context)
+	parser.initializing[name]--
 	if statement.Type == nil {
 		statement.Type = inferredType
+	} else if uninitialized && !isNever(statement.Type) && !statement.Type.IsAssignable(inferredType, context) {
+		parser.error(identToken, "'%s' needs an initial value, '%s' is not optional", name, statement.Type.ToString())
 	} else if !statement.Type.IsAssignable(inferredType, context) {
 		erroneousToken := statement.Value.Token()
 		if erroneousToken == nil {
@@ -126,7 +235,12 @@ func (parser *Parser) parseLetStatement(context *types.Context) *LetStatement {
 
 	_, ok := context.DefineMemberType(name, statement.Type)
 	if !ok {
-		parser.error(identToken, "Cannot redefine '%s'", name)
+		parser.redefinitionError(context, statement.Name)
+	} else if name != types.Discard {
+		parser.declarations = append(parser.declarations, declaration{context: context, identifier: statement.Name})
+		if context.Global {
+			parser.declaredAt[name] = parser.current()
+		}
 	}
 	return statement
 }
@@ -134,30 +248,84 @@ func (parser *Parser) parseLetStatement(context *types.Context) *LetStatement {
 func (parser *Parser) parseFunctionDefinitionStatement(context *types.Context) *FunctionDefinitionStatement {
 
 	statement := &FunctionDefinitionStatement{FuncToken: parser.current()}
+	if !parser.parseFunctionSignature(context, statement) {
+		return nil
+	}
+
+	functionContext := types.ExtendContext(context)
+	functionContext.ReturnType = statement.ReturnType
+	if statement.ThisType != nil {
+		functionContext.DefineMemberType("this", statement.ThisType)
+		if statement.ThisName != nil && statement.ThisName.Value != "this" {
+			functionContext.DefineMemberType(statement.ThisName.Value, statement.ThisType)
+		}
+	}
+	for i, parameter := range statement.Parameters {
+		_, ok := functionContext.DefineMemberType(parameter.Name.Value, parameter.Type)
+		// duplicate parameters have already been reported while parsing the parameter list
+		if !ok && !hasParameter(statement.Parameters[:i], parameter.Name.Value) {
+			parser.error(parameter.Token, "Cannot redefine '%s'", parameter.Name.Value)
+		}
+	}
+
+	if !parser.hoisted[statement.FuncToken] && !parser.defineFunction(context, statement) {
+		if statement.ThisType != nil {
+			parser.error(statement.Name.IdentToken, "Cannot redefine '%s'", statement.Name.Value)
+		} else {
+			parser.redefinitionError(context, statement.Name)
+		}
+	}
+
+	topLevelFunction := parser.topLevelFunction
+	if parser.functionDepth == 0 && context.Global && statement.ThisType == nil {
+		parser.topLevelFunction = statement.Name.Value
+	}
+	parser.functionDepth++
+	loopDepth := parser.loopDepth
+	parser.loopDepth = 0 // loops around the definition cannot be left from inside the function
+	statement.FunctionContext = types.CloneContext(functionContext)
+	statement.Body = parser.parseBlockStatement(statement.FunctionContext)
+	parser.loopDepth = loopDepth
+
+	returns := parser.doesReturn(types.CloneContext(functionContext), statement.Body)
+	if _, isVoid := statement.ReturnType.(*types.Void); !isVoid && !returns {
+		parser.missingReturnError(statement.Body)
+	}
+	parser.functionDepth--
+	parser.topLevelFunction = topLevelFunction
+
+	return statement
+}
+
+func (parser *Parser) parseFunctionSignature(context *types.Context, statement *FunctionDefinitionStatement) bool {
 
 	if parser.peek().Type == token.LParen {
 		parser.consume() // fn
 		parser.consume() // (
+		if parser.current().Type == token.Ident && parser.peek().Type == token.Colon {
+			nameToken := parser.consume()
+			statement.ThisName = &Identifier{IdentToken: nameToken, Value: nameToken.Literal}
+			parser.consume() // :
+		}
 		statement.ThisType = parser.parseType(context, TypeLowest)
 		if !parser.assertNext(token.RParen) || !parser.assertNext(token.DoubleColon) {
-			return nil
+			return false
 		}
 	}
 
 	if !parser.assertNext(token.Ident) {
-		return nil
+		return false
 	}
 	identToken := parser.current()
-	name := identToken.Literal
-	statement.Name = &Identifier{IdentToken: identToken, Value: name}
+	statement.Name = &Identifier{IdentToken: identToken, Value: identToken.Literal}
 
 	if !parser.assertNext(token.LParen) {
-		return nil
+		return false
 	}
 
 	statement.Parameters = parser.parseParameterList(context)
 	if statement.Parameters == nil {
-		return nil
+		return false
 	}
 	parser.consume()
 
@@ -166,75 +334,65 @@ func (parser *Parser) parseFunctionDefinitionStatement(context *types.Context) *
 	} else {
 		statement.ReturnType = parser.parseType(context, TypeLowest)
 		if !parser.assertNext(token.LBrace) {
-			return nil
+			return false
 		}
 	}
 
 	parameterTypes := make([]types.Type, 0)
-	functionContext := types.ExtendContext(context)
-	functionContext.ReturnType = statement.ReturnType
-	if statement.ThisType != nil {
-		functionContext.DefineMemberType("this", statement.ThisType)
-	}
 	for _, parameter := range statement.Parameters {
 		parameterTypes = append(parameterTypes, parameter.Type)
-		_, ok := functionContext.DefineMemberType(parameter.Name.Value, parameter.Type)
-		if !ok {
-			parser.error(parameter.Token, "Cannot redefine '%s'", parameter.Name.Value)
-		}
 	}
 
 	statement.FunctionType = &types.Function{
 		ParameterTypes: parameterTypes,
 		ReturnType:     statement.ReturnType,
 	}
+	return true
+}
 
+func (parser *Parser) defineFunction(context *types.Context, statement *FunctionDefinitionStatement) bool {
 	var ok bool
 	if statement.ThisType != nil {
-		_, ok = context.DefineTypeMemberType(name, statement.FunctionType, statement.ThisType)
+		_, ok = context.DefineTypeMemberType(statement.Name.Value, statement.FunctionType, statement.ThisType)
 	} else {
-		_, ok = context.DefineMemberType(name, statement.FunctionType)
-	}
-
-	if !ok {
-		parser.error(identToken, "Cannot redefine '%s'", name)
-	}
-
-	statement.FunctionContext = types.CloneContext(functionContext)
-	statement.Body = parser.parseBlockStatement(statement.FunctionContext)
-	if statement.Body == nil {
-		return nil
+		_, ok = context.DefineMemberType(statement.Name.Value, statement.FunctionType)
 	}
+	return ok
+}
 
-	if _, isVoid := statement.ReturnType.(*types.Void); !isVoid {
-		if returns := parser.doesReturn(types.CloneContext(functionContext), statement.Body); !returns {
-			erroneousToken := statement.Body.RBraceToken
-			if erroneousToken == nil {
-				erroneousToken = statement.Body.LBraceToken
-			}
-			parser.error(erroneousToken, "Missing return statement")
-		}
+// parses the condition of an if or while, rejecting a plain assignment as it is most likely a typo of ==
+func (parser *Parser) parseCondition(context *types.Context) Expression {
+	condition := parser.parseExpression(context, ExpressionLowest)
+	parser.getExpressionType(condition, context) // check type
+	if assignment, isAssignment := condition.(*AssignmentExpression); isAssignment && assignment.AssignToken.Type == token.Assign {
+		parser.error(assignment.AssignToken, "Assignment used as condition, did you mean '=='?")
 	}
-
-	return statement
+	return condition
 }
 
 func (parser *Parser) parseIfStatement(context *types.Context) *IfStatement {
 
 	statement := &IfStatement{IfToken: parser.consume()}
 
-	statement.Condition = parser.parseExpression(context, ExpressionLowest)
-	parser.getExpressionType(statement.Condition, context) // check type
+	statement.Condition = parser.parseCondition(context)
 	parser.consume()
 
 	statement.StatementContext = types.ExtendContext(context)
 	statement.Statement = parser.parseStatement(statement.StatementContext)
 
-	if parser.peek().Type == token.Else {
+	switch parser.peek().Type {
+	case token.Else:
 		parser.consume()
 		parser.consume()
 		statement.AlternativeContext = types.ExtendContext(context)
 		statement.Alternative = parser.parseStatement(statement.AlternativeContext)
+	case token.Elif: // same as 'else if', so it counts towards the nesting depth like the statement after 'else'
+		parser.consume()
+		statement.AlternativeContext = types.ExtendContext(context)
+		if parser.enter() {
+			statement.Alternative = parser.parseIfStatement(statement.AlternativeContext)
+		}
+		parser.leave()
 	}
 
 	return statement
@@ -244,18 +402,46 @@ func (parser *Parser) parseWhileStatement(context *types.Context) *WhileStatemen
 
 	statement := &WhileStatement{WhileToken: parser.consume()}
 
-	statement.Condition = parser.parseExpression(context, ExpressionLowest)
-	parser.getExpressionType(statement.Condition, context) // check type
+	statement.Condition = parser.parseCondition(context)
 	parser.consume()
 
 	statement.StatementContext = types.ExtendContext(context)
+	parser.loopDepth++
 	statement.Statement = parser.parseStatement(statement.StatementContext)
+	parser.loopDepth--
 
+	// the 'else' branch is not part of the loop, 'break' and 'continue' in it refer to an outer loop
+	if parser.peek().Type == token.Else {
+		parser.consume()
+		parser.consume()
+		statement.AlternativeContext = types.ExtendContext(context)
+		statement.Alternative = parser.parseStatement(statement.AlternativeContext)
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseBreakStatement() *BreakStatement {
+	statement := &BreakStatement{BreakToken: parser.current()}
+	if parser.loopDepth == 0 {
+		parser.error(statement.BreakToken, "Cannot use 'break' outside of a loop")
+	}
+	parser.assertStatementEnd()
+	return statement
+}
+
+func (parser *Parser) parseContinueStatement() *ContinueStatement {
+	statement := &ContinueStatement{ContinueToken: parser.current()}
+	if parser.loopDepth == 0 {
+		parser.error(statement.ContinueToken, "Cannot use 'continue' outside of a loop")
+	}
+	parser.assertStatementEnd()
 	return statement
 }
 
 func (parser *Parser) parseTypeDefinitionStatement(context *types.Context) *TypeDefinitionStatement {
 
+	typeToken := parser.current()
 	if !parser.assertNext(token.Ident) {
 		return nil
 	}
@@ -269,17 +455,83 @@ func (parser *Parser) parseTypeDefinitionStatement(context *types.Context) *Type
 
 	parser.consume()
 	statement := &TypeDefinitionStatement{IdentToken: identToken, Name: ident}
-	statement.Type = parser.parseType(context, TypeLowest)
 
 	switch name {
 	case types.TypeNull, types.TypeVoid, types.TypeString, types.TypeInt, types.TypeFloat, types.TypeBool:
 		parser.error(identToken, "Cannot re-declare primitive '%s'", name)
+		statement.Type = parser.parseType(context, TypeLowest)
 	default:
-		if _, ok := context.DefineType(name, statement.Type); !ok {
-			parser.error(identToken, "Cannot re-declare type '%s'", name)
+		if parser.current().Type == token.Iface {
+			statement.Type = parser.parseIfaceDefinition(context, typeToken, identToken)
+		} else {
+			statement.Type = parser.parseType(context, TypeLowest)
+			if _, ok := context.DefineType(name, statement.Type); !ok {
+				parser.error(identToken, "Cannot re-declare type '%s'", name)
+			}
 		}
 	}
 
-	parser.assertNext(token.Semi)
+	parser.assertStatementEnd()
 	return statement
 }
+
+// interfaces are declared before their members are parsed, so that the members can refer to the interface itself
+func (parser *Parser) parseIfaceDefinition(context *types.Context, typeToken *token.Token, identToken *token.Token) types.Type {
+	name := identToken.Literal
+	declared, _ := context.GetTypeStrict(name)
+	iface, isIface := declared.(*types.Iface)
+	if !parser.hoisted[typeToken] || !isIface {
+		iface = &types.Iface{Name: name}
+		if _, ok := context.DefineType(name, iface); !ok {
+			parser.error(identToken, "Cannot re-declare type '%s'", name)
+		}
+	}
+
+	if literal, isIface := parser.parseType(context, TypeLowest).(*types.Iface); isIface {
+		iface.Members = literal.Members
+	}
+	if iface.Members == nil {
+		iface.Members = make(map[string]types.Type)
+	}
+	return iface
+}
+
+func (parser *Parser) missingReturnError(body *BlockStatement) {
+	erroneousToken := body.RBraceToken
+	if erroneousToken == nil {
+		erroneousToken = body.LBraceToken
+	}
+
+	if len(body.Statements) > 0 {
+		switch lastStatement := body.Statements[len(body.Statements)-1].(type) {
+		case *IfStatement:
+			parser.missingBranchReturnError(lastStatement)
+			return
+		case *WhileStatement:
+			parser.error(lastStatement.WhileToken, "Missing return statement after 'while' loop")
+			return
+		}
+	}
+
+	parser.error(erroneousToken, "Missing return statement")
+}
+
+// reports the first branch of an 'if' or 'else if' chain that does not return, or the missing 'else'
+func (parser *Parser) missingBranchReturnError(ifStatement *IfStatement) {
+	for {
+		if !parser.doesReturnSilently(ifStatement.StatementContext, ifStatement.Statement) {
+			parser.error(ifStatement.Statement.Token(), "Missing return statement: not all branches of 'if' return")
+			return
+		}
+		switch alternative := ifStatement.Alternative.(type) {
+		case nil:
+			parser.error(ifStatement.IfToken, "Missing return statement: 'if' without 'else' does not return in all branches")
+			return
+		case *IfStatement:
+			ifStatement = alternative
+		default:
+			parser.error(alternative.Token(), "Missing return statement: not all branches of 'if' return")
+			return
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"bananascript/src/errors"
 	"bananascript/src/lexer"
 	"bananascript/src/token"
 	"bananascript/src/types"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/assert"
+	"strings"
 	"testing"
 )
 
@@ -20,6 +23,15 @@ func TestStatementParser(t *testing.T) {
 		},
 	)
 
+	assertStatement(t,
+		"a := 5;",
+		&LetStatement{
+			Name:  &Identifier{Value: "a"},
+			Type:  &types.Int{},
+			Value: &IntegerLiteral{Value: 5},
+		},
+	)
+
 	assertStatement(t,
 		"if a == 5 || a == 3 println(\"test\");",
 		&IfStatement{
@@ -61,9 +73,593 @@ func TestStatementParser(t *testing.T) {
 	assertError(t, "fn test(noType) {}")
 	assertError(t, "fn noReturn() string {}")
 	assertError(t, "{ type test := iface { abc: fn() void; }; let a: test = 2; }")
+	assertError(t, "{ a := 5; a := 6; }")
+	assertError(t, "{ let a := 5; a := \"test\"; }")
+	assertError(t, "{ let _ := 5; let a := _; }")
+	assertError(t, "{ fn test(_: int) int { return _; } }")
 
 	assertNoError(t, "{ type str := string; let a: str = \"test\"; }")
 	assertNoError(t, "{ type test := iface { }; let a: test = 0; let b: test = \"\"; let c: test = false; }")
+	assertNoError(t, "{ a := 5; let b: int = a; { a := \"test\"; } }")
+	assertNoError(t, "{ let _ := 5; let _ := \"test\"; _ := true; }")
+	assertNoError(t, "{ fn test(_: int, _: string) {} }")
+}
+
+func TestRecursiveInterfaces(t *testing.T) {
+	assertProgramNoError(t, `
+		type Counter := iface { next: fn() Counter?; };
+		fn (int)::next() int? { if this > 0 { return this - 1; } return null; }
+		let a: Counter = 3;
+	`)
+	assertProgramNoError(t, `
+		type Comparable := iface { compare: fn(Comparable) int; };
+		fn (int)::compare(other: int) int { return this - other; }
+		let a: Comparable = 1;
+	`)
+	assertProgramNoError(t, `
+		let convert: fn(A) B = toB;
+		type A := iface { toB: fn() B; };
+		type B := iface { toA: fn() A; };
+		fn toB(a: A) B { return a.toB(); }
+		fn (int)::toB() string { return "b"; }
+		fn (string)::toA() int { return 1; }
+		let a: A = 1;
+		let b: B = "b";
+	`)
+	assertProgramErrorMessage(t, `
+		type Counter := iface { next: fn() Counter; };
+		fn (string)::previous() string { return this; }
+		let a: Counter = "a";
+	`, "Type 'string' is not assignable to 'Counter'")
+	assertProgramError(t, "type Counter := iface { }; type Counter := iface { };")
+	assertError(t, "{ type A := iface { toB: fn() B; }; type B := iface { toA: fn() A; }; }")
+}
+
+func TestConcurrentInterfaceChecks(t *testing.T) {
+	theParser := New(lexer.FromCode(`
+		type Comparable := iface { compare: fn(Comparable) int; };
+		fn (int)::compare(other: int) int { return this - other; }
+	`))
+	program, errors := theParser.ParseProgram(types.NewContext())
+	assert.Equal(t, len(errors), 0)
+	comparable, _ := program.Context.GetType("Comparable")
+
+	results := make(chan bool)
+	for i := 0; i < 8; i++ {
+		go func() {
+			assignable := true
+			for j := 0; j < 1000; j++ {
+				assignable = assignable && comparable.IsAssignable(&types.Int{}, program.Context)
+			}
+			results <- assignable
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		assert.Assert(t, <-results)
+	}
+}
+
+func TestBlockExpression(t *testing.T) {
+	assertNoError(t, "{ let a: int = { let b := 2; b * 2 }; }")
+	assertNoError(t, "{ let a: string = { \"a\" + 1 }; }")
+	assertNoError(t, "{ let a: void = { \"a\" + 1; }; }")
+	assertError(t, "{ let a: string = { \"a\" + 1; }; }")
+	assertNoError(t, "{ let a: void = {}; }")
+	assertNoError(t, "{ let a: int = { { 5 } }; }")
+	assertNoError(t, "{ let a: int = { if true { 5 } else { 6 } }; }")
+	assertNoError(t, "{ let a: int = { let b := 1; if b > 1 { b } elif b < 1 { -b } else if b == 1 { { 0 } } else { 1 } }; }")
+	assertNoError(t, "{ let a: void = { if true { 5 } }; }")
+	assertNoError(t, "{ let a: void = { { 5 }; }; }")
+	assertError(t, "{ let a: string = { { 5 } }; }")
+	assertError(t, "{ let a: string = { if true { 5 } else { 6 } }; }")
+	assertError(t, "{ let a: string = { 5 }; }")
+	assertError(t, "{ let a := { let b := 5; b }; let c := b; }")
+	assertError(t, "fn test() int { let a := { return 5; }; return a; }")
+}
+
+func TestEmptyBodies(t *testing.T) {
+	assertNoError(t, "{}")
+	assertNoError(t, "{ let a := 0; while a < 5 {} }")
+	assertNoError(t, "{ let a := 0; while a++ < 5; }")
+	assertNoError(t, "{ if true {} }")
+	assertNoError(t, "{ if true {} else {} }")
+	assertNoError(t, "{ if true; else; }")
+	assertNoError(t, "{ {} {{}} }")
+}
+
+func TestWhileScoping(t *testing.T) {
+	assertNoError(t, "{ let a := 0; while a < 5 { let b := a; a = b + 1; } }")
+	assertError(t, "{ while true { let a := 1; } a++; }")
+	assertError(t, "{ while a < 5 { let a := 0; } }")
+}
+
+func TestWhileElse(t *testing.T) {
+	assertNoError(t, "{ let a := 0; while a < 5 { a++; } else { let b := a; } }")
+	assertNoError(t, "{ while true { while false {} else { break; } } }")
+	assertErrorMessage(t, "{ while false {} else { break; } }", "Cannot use 'break' outside of a loop")
+	assertErrorMessage(t, "{ while false { let a := 1; } else { a++; } }", "Cannot resolve reference to 'a'")
+	assertErrorMessage(t, "fn test() int { while false { return 1; } else { return 2; } }", "Missing return statement after 'while' loop")
+	assertStatement(t, "while false {} else {}", &WhileStatement{
+		Condition:   &BooleanLiteral{Value: false},
+		Statement:   &BlockStatement{Statements: []Statement{}},
+		Alternative: &BlockStatement{Statements: []Statement{}},
+	})
+}
+
+func TestBreakContinue(t *testing.T) {
+	assertNoError(t, "{ while true { if true { break; } continue; } }")
+	assertNoError(t, "{ while true { while true { break } continue } }")
+	assertErrorMessage(t, "{ break; }", "Cannot use 'break' outside of a loop")
+	assertErrorMessage(t, "{ if true { continue; } }", "Cannot use 'continue' outside of a loop")
+	assertErrorMessage(t, "{ while true { fn test() { break; } } }", "Cannot use 'break' outside of a loop")
+	assertErrorMessage(t, "{ while true { let a := { continue; }; } }", "Cannot use 'continue' outside of a loop")
+}
+
+func TestMainSignature(t *testing.T) {
+	assertProgramNoError(t, "fn main() int { return 0; }")
+	assertProgramNoError(t, "fn main() {}")
+	assertProgramNoError(t, "fn (int)::main(a: string) {}")
+	assertProgramNoError(t, "{ let main := 5; }")
+	assertProgramErrorMessage(t, "fn main(a: int) int { return a; }", "'main' must be a function without parameters returning 'int' or 'void'")
+	assertProgramErrorMessage(t, "fn main() string { return \"\"; }", "'main' must be a function without parameters returning 'int' or 'void'")
+	assertProgramErrorMessage(t, "let main := 5;", "'main' must be a function without parameters returning 'int' or 'void'")
+}
+
+func TestStrayTokens(t *testing.T) {
+	assertProgramErrorMessage(t, "let a := 1; }", "Unexpected '}' without matching '{'")
+	assertProgramErrorMessage(t, "}", "Unexpected '}' without matching '{'")
+	assertProgramErrorMessage(t, "let a := 1; *", "Unexpected '*'")
+	assertProgramErrorMessage(t, "let a := 1 )", "Expected ';', got ')' instead")
+	assertProgramErrorMessage(t, "let a := 1; ) let b := a;", "Unexpected ')'")
+	assertProgramErrorMessage(t, "else", "Unexpected 'else'")
+	assertProgramErrorMessage(t, "let a := 1 +", "Unexpected EOF")
+	assert.Equal(t, len(parseProgram("fn test() {} }}")), 2)
+	assertErrorMessage(t, "{ let a := 1 ) }", "Expected ';', got ')' instead")
+	assert.Equal(t, len(parseProgram("let a := 1 ) let b := 2 )")), 2)
+}
+
+func TestErrorsAtSamePosition(t *testing.T) {
+	theParser := New(lexer.FromCode("a"))
+	theParser.error(theParser.current(), "First")
+	theParser.error(theParser.current(), "Second")
+	assert.Equal(t, len(theParser.errors), 2)
+
+	// a failed statement end does not hide the other errors of the statement
+	errors := parseProgram("let a: int = \"a\" let b := 1;")
+	assert.Equal(t, len(errors), 2)
+	assert.Equal(t, errors[0].Message, "Expected ';', got 'let' instead")
+	assert.Equal(t, errors[1].Message, "Type 'string' is not assignable to 'int'")
+}
+
+func TestDuplicateParameters(t *testing.T) {
+	assertNoError(t, "fn test(x: int, y: int) int { return x + y; }")
+	assertNoError(t, "fn test(_: int, x: int, _: string) int { return x; }")
+	assertErrorMessage(t, "fn test(x: int, x: int) int { return x; }", "Duplicate parameter 'x'")
+	assertErrorMessage(t, "fn test(x: int, y: string, x: string) {}", "Duplicate parameter 'x'")
+	assertErrorMessage(t, "fn (int)::test(x: int, x: float) {}", "Duplicate parameter 'x'")
+	assertProgramErrorMessage(t, "fn test(x: int, x: int) {}", "Duplicate parameter 'x'")
+}
+
+func TestErrorFields(t *testing.T) {
+	parserErrors := parseProgram("let a := 1;\nlet b := a +;")
+	assert.Equal(t, len(parserErrors), 1)
+
+	parserError := parserErrors[0]
+	assert.Equal(t, parserError.Message, "Unexpected ';'")
+	assert.Equal(t, parserError.Line, 2)
+	assert.Equal(t, parserError.Col, 13)
+	assert.Equal(t, parserError.Token.Type, token.Semi)
+
+	var err error = parserError
+	assert.Equal(t, err.Error(), "2:13: Unexpected ';'")
+}
+
+func TestNamedReceiver(t *testing.T) {
+	named := "type Named := iface { name: fn() string; };"
+	assertProgramNoError(t, named+"fn (self: Named)::greet() string { return \"Hi \" + self.name(); }")
+	assertProgramNoError(t, named+"fn (self: Named)::greet() string { return this.name() + self.name(); }")
+	assertProgramNoError(t, "fn (n: int)::double() int { return n * 2; } let a: int = (3).double();")
+	assertProgramNoError(t, "fn (this: int)::double() int { return this * 2; }")
+	assertProgramErrorMessage(t, named+"fn (self: Named)::greet() string { return self.missing(); }",
+		"Member 'missing' does not exist on 'Named'")
+	assertProgramErrorMessage(t, "fn (n: int)::half() string { return n / 2; }", "Type 'int' is not assignable to 'string'")
+	assertProgramErrorMessage(t, "fn (n: int)::add(n: int) int { return n; }", "Cannot redefine 'n'")
+	assertProgramErrorMessage(t, "fn (n: int)::a() {} fn b() int { return n; }", "Cannot resolve reference to 'n'")
+}
+
+func TestEmptyProgram(t *testing.T) {
+	for _, input := range []string{"", "  \n\t", "// comment", "/* comment */"} {
+		program, parserErrors := New(lexer.FromCode(input)).ParseProgram(types.NewContext())
+		assert.Equal(t, len(parserErrors), 0, "%q", input)
+		assert.Equal(t, len(program.Statements), 0, "%q", input)
+	}
+}
+
+func TestHoisting(t *testing.T) {
+	assertProgramNoError(t, "let a := test(); fn test() int { return 1; }")
+	assertProgramNoError(t, "fn a() int { return b(); } fn b() int { return a(); }")
+	assertProgramNoError(t, "let a := (1).next(); fn (int)::next() int { return this + 1; }")
+	assertProgramError(t, "fn a() {} fn a() {}")
+	assertProgramError(t, "let a := 5; fn a() {}")
+	assertProgramError(t, "{ let a := test(); fn test() int { return 1; } }")
+}
+
+func TestHoistedFunctionReads(t *testing.T) {
+	assertProgramNoError(t, "let v := 1; let a := read(); fn read() int { return v; }")
+	assertProgramNoError(t, "let v := 1; write(); fn write() { v = 2; }")
+	assertProgramNoError(t, "let v := 1; fn read() int { let v := 2; return v; } let a := read();")
+	assertProgramNoError(t, "fn get() int { return read(); } let v := 1; let a := get(); fn read() int { return v; }")
+	assertProgramErrorMessage(t, "let a := read(); let v := 1; fn read() int { return v; }",
+		"Cannot use 'read' here, it reads 'v' which is not declared yet")
+	assertProgramErrorMessage(t, "let v := read(); fn read() int { return v; }",
+		"Cannot use 'read' here, it reads 'v' which is not declared yet")
+	assertProgramErrorMessage(t, "{ write(); } let v := 1; fn write() { v = 2; }",
+		"Cannot use 'write' here, it reads 'v' which is not declared yet")
+	assertProgramErrorMessage(t, "let a := get(); let v := 1; fn get() int { return read(); } fn read() int { return v; }",
+		"Cannot use 'get' here, it reads 'v' which is not declared yet")
+	assertProgramErrorMessage(t, "fn get() int { return read(); } let a := get(); let v := 1; fn read() int { return v; }",
+		"Cannot use 'get' here, it reads 'v' which is not declared yet")
+
+	parserErrors := parseProgram("let a := f()\nlet v := 3\nfn f() int { return v }")
+	assert.Equal(t, len(parserErrors), 1)
+	assert.Equal(t, parserErrors[0].Line, 1)
+	assert.Equal(t, parserErrors[0].Col, 10)
+}
+
+func TestReturnPlacement(t *testing.T) {
+	assertProgramErrorMessage(t, "return 5;", "Cannot return outside of a function")
+	assertProgramErrorMessage(t, "{ return; }", "Cannot return outside of a function")
+	assertProgramErrorMessage(t, "while true { return; }", "Cannot return outside of a function")
+	assertErrorMessage(t, "fn test() { return 5; }", "Cannot return a value from a void function")
+	assertErrorMessage(t, "fn test(a: int) { while a > 0 { if a == 1 { return a; } } }", "Cannot return a value from a void function")
+	assertErrorMessage(t, "fn test() { let a := { return; }; }", "Cannot return from a block expression")
+	assertErrorMessage(t, "fn test(a: int) int { while a > 0 { return \"a\"; } return 1; }", "Type 'string' is not assignable to 'int'")
+	assertErrorMessage(t, "fn test(a: int) int { if a > 0 { a++; } else { return \"a\"; } return 1; }", "Type 'string' is not assignable to 'int'")
+	assertErrorMessage(t, "fn test() { return; test(); }", "Unreachable code")
+	assertNoError(t, "fn test(a: int) { if a > 0 { return; } a++; }")
+}
+
+func TestAnnotations(t *testing.T) {
+	theParser := New(lexer.FromCode(`
+		@deprecated @custom fn old() int { return 1; }
+		@inline
+		fn new() int { return old(); }
+		@exported let a := 1, b := 2;
+		fn plain() {}
+	`))
+	program, errors := theParser.ParseProgram(types.NewContext())
+	assert.Equal(t, len(errors), 0)
+	annotationNames := func(annotations []*Identifier) []string {
+		names := make([]string, 0)
+		for _, annotation := range annotations {
+			names = append(names, annotation.Value)
+		}
+		return names
+	}
+	assert.DeepEqual(t, annotationNames(program.Statements[0].(*FunctionDefinitionStatement).Annotations),
+		[]string{"deprecated", "custom"})
+	assert.DeepEqual(t, annotationNames(program.Statements[1].(*FunctionDefinitionStatement).Annotations), []string{"inline"})
+	for _, declaration := range program.Statements[2].(*MultiLetStatement).Declarations {
+		assert.DeepEqual(t, annotationNames(declaration.Annotations), []string{"exported"})
+	}
+	assert.Equal(t, len(program.Statements[3].(*FunctionDefinitionStatement).Annotations), 0)
+
+	assertProgramNoError(t, "let a := f(); @deprecated fn f() int { return 1; }")
+	assertProgramErrorMessage(t, "@deprecated a := 1;", "Annotations can only be used on 'fn' and 'let'")
+	assertProgramErrorMessage(t, "@ fn f() {}", "Expected identifier, got 'fn' instead")
+}
+
+func TestUninitializedLet(t *testing.T) {
+	assertNoError(t, "{ let a: int?; a = 1; }")
+	assertNoError(t, "{ type empty := iface { }; let a: string? = null; let b: empty; }")
+	assertNoError(t, "{ let a; a = null; }")
+	assertErrorMessage(t, "let a: int;", "'a' needs an initial value, 'int' is not optional")
+	assertErrorMessage(t, "let s: string;", "'s' needs an initial value, 'string' is not optional")
+	assertErrorMessage(t, "let a: int = null;", "Type 'null' is not assignable to 'int'")
+	assertProgramErrorMessage(t, "let a: int?, b: bool;", "'b' needs an initial value, 'bool' is not optional")
+}
+
+func TestReturnTypes(t *testing.T) {
+	assertNoError(t, "fn test() string { return \"a\"; }")
+	assertNoError(t, "fn test() string? { return null; }")
+	assertNoError(t, "fn test(a: int) int? { if a > 0 { return a; } return null; }")
+	assertNoError(t, "fn test() float { fn inner() int { return 1; } return 1.5; }")
+	assertNoError(t, "fn (int)::half() float { return this / 2.0; }")
+	assertErrorMessage(t, "fn test() string { return 1; }", "Type 'int' is not assignable to 'string'")
+	assertErrorMessage(t, "fn test() int { return null; }", "Type 'null' is not assignable to 'int'")
+	assertErrorMessage(t, "fn test() int { return 1.5; }", "Type 'float' is not assignable to 'int'")
+	assertErrorMessage(t, "fn test() int { fn inner() string { return 1; } return 1; }", "Type 'int' is not assignable to 'string'")
+	assertErrorMessage(t, "fn test() string { fn inner() int { return 1; } return inner(); }", "Type 'int' is not assignable to 'string'")
+	assertErrorMessage(t, "fn (int)::text() string { return this; }", "Type 'int' is not assignable to 'string'")
+}
+
+func TestBareReturn(t *testing.T) {
+	assertNoError(t, "fn test() { return; }")
+	assertNoError(t, "fn test(a: int) { if a > 0 { return; } a++; }")
+	assertNoError(t, "fn test() int { return 1; }")
+	assertNoError(t, "fn (int)::test() { return; }")
+	assertErrorMessage(t, "fn test() int { return; }", "Type 'void' is not assignable to 'int'")
+	assertErrorMessage(t, "fn test() int? { return; }", "Type 'void' is not assignable to 'int?'")
+	assertErrorMessage(t, "fn test() { fn inner() string { return; } }", "Type 'void' is not assignable to 'string'")
+	assertErrorMessage(t, "fn test() { return 1; }", "Cannot return a value from a void function")
+	assertErrorMessage(t, "fn (int)::test() { return this; }", "Cannot return a value from a void function")
+	assertErrorMessage(t, "fn test() int { fn inner() { return 1; } return 1; }", "Cannot return a value from a void function")
+}
+
+func TestIfExpression(t *testing.T) {
+	assertNoError(t, "{ let a := 1; let b: int = if a > 0 { a } else { 0 }; }")
+	assertNoError(t, "{ let a := 1; let b: string = if a > 0 { \"a\" } else if a < 0 { \"b\" } else { \"c\" }; }")
+	assertNoError(t, "{ let a: int? = if true { 1 } else { null }; }")
+	assertNoError(t, "{ let a := if true { null } else { 1 }; a = null; }")
+	assertErrorMessage(t, "{ let a := if true { 1 }; }", "'if' expression is missing an 'else' branch")
+	assertErrorMessage(t, "{ let a := if true { 1 } else { \"a\" }; }", "Type mismatch: branches of 'if' expression are 'int' and 'string'")
+	assertErrorMessage(t, "{ let a: string = if true { 1 } else { 2 }; }", "Type 'int' is not assignable to 'string'")
+	assertError(t, "fn test() int { let a := if true { return 1; } else { 2 }; return a; }")
+}
+
+func TestElif(t *testing.T) {
+	assertSameStatement(t,
+		"if a > 0 { a = 1; } elif a < 0 { a = 2; } elif a == 0 { a = 3; } else { a = 4; }",
+		"if a > 0 { a = 1; } else if a < 0 { a = 2; } else if a == 0 { a = 3; } else { a = 4; }",
+	)
+	assertSameStatement(t,
+		"let b := if a > 0 { 1 } elif a < 0 { 2 } else { 3 };",
+		"let b := if a > 0 { 1 } else if a < 0 { 2 } else { 3 };",
+	)
+	assertNoError(t, "{ let a := 1; if a > 0 { let b := 1; } elif a < 0 { let b := 2; } else { let b := 3; } }")
+	assertNoError(t, "{ let a := 1; let b: string = if a > 0 { \"a\" } elif a < 0 { \"b\" } else { \"c\" }; }")
+	assertErrorMessage(t, "{ let a := 1; if a > 0 { let b := 1; } elif a < 0 { b = 2; } }", "Cannot resolve reference to 'b'")
+	assertErrorMessage(t, "{ let a := 1; let b := if a > 0 { 1 } elif a < 0 { 2 }; }", "'if' expression is missing an 'else' branch")
+}
+
+func TestUnaryPlus(t *testing.T) {
+	assertNoError(t, "{ let a: int = +5; let b: float = +3.2; let c: int = a + +a; }")
+	assertErrorMessage(t, "{ let a := +\"x\"; }", "Type mismatch: +string")
+	assertErrorMessage(t, "{ let a := +true; }", "Type mismatch: +bool")
+}
+
+func TestAutomaticSemicolons(t *testing.T) {
+	assertProgramNoError(t, "let a := 1\nlet b: int = a\nb = a + b\ntype num := int\nfn test() int { return a }")
+	assertProgramNoError(t, "let a: int?\nlet b: string?\nfn test() {\n\treturn\n}")
+	assertProgramNoError(t, "let a := 1 +\n\t2 *\n\t3\nlet b := a\n\t+ 1")
+	assertProgramNoError(t, "let a := { let b := 1\n b * 2 }\nfn test() int { return a }")
+	assertProgramNoError(t, "let a := 1\nlet b := a\n++a")
+	assertProgramNoError(t, "let a := test()\nfn test() int { return 1 }")
+	assertProgramError(t, "let a := 1 let b := 2")
+	assertProgramError(t, "let a := 1\nlet b := a a")
+	assertProgramError(t, "fn test() int {\n\treturn\n\t1\n}")
+}
+
+func TestSelfReference(t *testing.T) {
+	assertErrorMessage(t, "{ let x := x + 1; }", "Cannot reference 'x' in its own initializer")
+	assertErrorMessage(t, "{ let x: int = 2 * x; }", "Cannot reference 'x' in its own initializer")
+	assertErrorMessage(t, "{ x := { let y := 1; x + y }; }", "Cannot reference 'x' in its own initializer")
+	assertErrorMessage(t, "{ let x := y; }", "Cannot resolve reference to 'y'")
+	assertNoError(t, "{ let x := 1; { let x := x + 1; } }")
+	assertNoError(t, "{ let x := \"test\"; { let x: string = x + \"!\"; } }")
+}
+
+func TestOperatorMethods(t *testing.T) {
+	assertProgramNoError(t, "fn (bool)::__add__(other: bool) bool { return this || other; } let a: bool = true + false;")
+	assertProgramNoError(t, "fn (string)::__sub__(other: int) int { return other; } let a: int = \"test\" - 1;")
+	assertProgramError(t, "fn (bool)::__add__(other: bool) bool { return this || other; } let a := true + 1;")
+	assertProgramError(t, "fn (bool)::__add__(other: bool) { } let a := true + false;")
+	assertProgramError(t, "fn (bool)::__add__(other: bool) bool { return this || other; } let a := true - false;")
+	assertProgramError(t, "fn (bool)::__add__(other: bool) bool { return this || other; } let a: string = true + false;")
+}
+
+func TestBooleanComparison(t *testing.T) {
+	assertErrorMessage(t, "{ let a := true < false; }", "Operator '<' is not defined for 'bool'")
+	assertErrorMessage(t, "{ let a := 1 >= true; }", "Operator '>=' is not defined for 'bool'")
+	assertNoError(t, "{ let a: bool = true == false; }")
+	assertNoError(t, "{ let a: bool = true != false; }")
+	assertProgramNoError(t, "fn (bool)::__lt__(other: bool) bool { return !this && other; } let a: bool = false < true;")
+}
+
+func TestNullComparison(t *testing.T) {
+	assertErrorMessage(t, "{ let a := null < 5; }", "Operator '<' is not defined for 'null'")
+	assertErrorMessage(t, "{ let a := 5 >= null; }", "Operator '>=' is not defined for 'null'")
+	assertErrorMessage(t, "{ let a: int? = 1; let b := a > 0; }", "Operator '>' is not defined for 'int?'")
+	assertNoError(t, "{ let a: bool = null == null; }")
+	assertNoError(t, "{ let a: int? = 1; let b: bool = null != a; let c := a == 1; }")
+}
+
+func TestMemberChains(t *testing.T) {
+	extensions := `
+		type Named := iface { name: fn() string; };
+		fn (int)::half() float { return this / 2.0; }
+		fn (float)::label() string { return "f" + this; }
+		fn (float)::named() Named { return 1; }
+		fn (int)::name() string { return "one"; }
+	`
+	assertProgramNoError(t, extensions+"let a: string = (3).half().label();")
+	assertProgramNoError(t, extensions+"let a: string = (3).half().named().name();")
+	assertProgramNoError(t, extensions+"let a: fn() string = (3).half().label;")
+	assertProgramErrorMessage(t, extensions+"let a := (3).half().missing();", "Member 'missing' does not exist on 'float'")
+	assertProgramErrorMessage(t, extensions+"let a := (3).half().named().half();", "Member 'half' does not exist on 'Named'")
+	assertProgramErrorMessage(t, extensions+"let a: int = (3).half().label();", "Type 'string' is not assignable to 'int'")
+}
+
+func TestLogicalOperators(t *testing.T) {
+	assertNoError(t, "{ let a: bool = true && false; }")
+	assertNoError(t, "{ let a: bool = 1 || \"\"; }")
+	assertNoError(t, "{ let a: int? = null; let b: bool = a && 1.5; }")
+	assertErrorMessage(t, "{ let a: int = true && false; }", "Type 'bool' is not assignable to 'int'")
+	assertErrorMessage(t, "{ fn f() {} let a := f() && true; }", "Operator '&&' is not defined for 'void'")
+	assertErrorMessage(t, "{ fn f() {} let a := true || f(); }", "Operator '||' is not defined for 'void'")
+
+	strictErrors := func(input string) []string {
+		theParser := New(lexer.FromCode(input))
+		theParser.Strict()
+		_, parserErrors := theParser.ParseProgram(types.NewContext())
+		messages := make([]string, len(parserErrors))
+		for i, err := range parserErrors {
+			messages[i] = err.Message
+		}
+		return messages
+	}
+	assert.DeepEqual(t, strictErrors("let a: bool = true && !false || 1 < 2;"), []string{})
+	assert.DeepEqual(t, strictErrors("let a := 1 && 2;"), []string{"Operator '&&' is not defined for 'int'"})
+	assert.DeepEqual(t, strictErrors("let a := true || \"a\";"), []string{"Operator '||' is not defined for 'string'"})
+	assert.DeepEqual(t, strictErrors("let a: bool? = true; let b := a && true;"), []string{"Operator '&&' is not defined for 'bool?'"})
+}
+
+func TestInOperator(t *testing.T) {
+	assertNoError(t, "{ let a: bool = \"b\" in \"abc\"; }")
+	assertNoError(t, "{ let a: bool = \"a\" + \"b\" in \"abc\" == true; }")
+	assertErrorMessage(t, "{ let a := 1 in \"abc\"; }", "Type mismatch: int in string")
+	assertErrorMessage(t, "{ let a := \"a\" in 5; }", "Type mismatch: string in int")
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	assertNoError(t, "{ let a := 7; a += 1; a -= 2; a *= 3; a /= 2; a %= 4; a **= 2; }")
+	assertNoError(t, "{ let a := 7.5; a %= 2; a **= 0.5; }")
+	assertErrorMessage(t, "{ let a := 7; a **= 0.5; }", "Type 'float' is not assignable to 'int'")
+	assertErrorMessage(t, "{ let a := 7; a %= 1.5; }", "Type 'float' is not assignable to 'int'")
+	assertErrorMessage(t, "{ let a := \"a\"; a %= 2; }", "Type mismatch: string % int")
+	assertErrorMessage(t, "{ let a := true; a **= 2; }", "Type mismatch: bool ** int")
+}
+
+func TestChainedAssignment(t *testing.T) {
+	assertNoError(t, "{ let a := 1; let b := 2; a = b = 5; }")
+	assertNoError(t, "{ let a := 1; let b := 2; let c := 3; a += b = c *= 2; }")
+	assertNoError(t, "{ let a: int? = 1; let b := 2; a = b = 5; }")
+	assertErrorMessage(t, "{ let a := \"\"; let b := 2; a = b = 5; }", "Type 'int' is not assignable to 'string'")
+	assertErrorMessage(t, "{ let a := 1; let b := \"\"; a = b = 5; }", "Type 'int' is not assignable to 'string'")
+	assertErrorMessage(t, "{ let a := 1; a = 2 = 3; }", "Invalid identifier")
+	assertStatement(t, "a = b = 5;", &ExpressionStatement{Expression: &AssignmentExpression{
+		Name: &Identifier{Value: "a"},
+		Expression: &AssignmentExpression{
+			Name:       &Identifier{Value: "b"},
+			Expression: &IntegerLiteral{Value: 5},
+		},
+	}})
+}
+
+func TestAssignmentInCondition(t *testing.T) {
+	assertErrorMessage(t, "{ let x := 1; if (x = 5) {} }", "Assignment used as condition, did you mean '=='?")
+	assertErrorMessage(t, "{ let x := 1; while x = 5 {} }", "Assignment used as condition, did you mean '=='?")
+	assertErrorMessage(t, "{ let x := 1; let a := if x = 5 { 1 } else { 2 }; }", "Assignment used as condition, did you mean '=='?")
+	assertNoError(t, "{ let x := 1; if (x == 5) {} }")
+	assertNoError(t, "{ let x := 1; while (x -= 1) > 0 {} }")
+}
+
+func TestMultiLet(t *testing.T) {
+	assertNoError(t, "{ let a := 1, b: string = \"b\"; let c: int = a; let d: string = b; }")
+	assertNoError(t, "{ let a := 1, b := a + 1, c: int?; c = b; }")
+	assertErrorMessage(t, "{ let a := 1, a := 2; }", "Cannot redefine 'a'")
+	assertErrorMessage(t, "{ let a := 1, b: int = \"b\"; }", "Type 'string' is not assignable to 'int'")
+	assertErrorMessage(t, "{ let a := 1, 2; }", "Expected identifier, got integer literal instead")
+	assertErrorMessage(t, "{ a := 1, b := 2; }", "Expected ';', got ',' instead")
+}
+
+func TestNestedFunctions(t *testing.T) {
+	assertProgramNoError(t, "fn outer() int { let a := 1; fn inner() int { return a; } return inner(); }")
+	assertProgramErrorMessage(t, "fn outer() { fn inner() {} } inner();", "Cannot resolve reference to 'inner'")
+	// only top-level functions are hoisted
+	assertProgramErrorMessage(t, "fn outer() { inner(); fn inner() {} }", "Cannot resolve reference to 'inner'")
+}
+
+func TestNestingDepth(t *testing.T) {
+	assertProgramErrorMessage(t, "let a := "+strings.Repeat("(", 5000)+"1"+strings.Repeat(")", 5000)+";",
+		"Maximum nesting depth exceeded")
+	assertProgramErrorMessage(t, strings.Repeat("{", 5000)+strings.Repeat("}", 5000), "Maximum nesting depth exceeded")
+	assertProgramErrorMessage(t, strings.Repeat("if true ", 5000)+"{}", "Maximum nesting depth exceeded")
+	assertProgramErrorMessage(t, "let a: "+strings.Repeat("fn(", 5000)+"int"+strings.Repeat(")", 5000)+" = null;",
+		"Maximum nesting depth exceeded")
+	assertProgramErrorMessage(t, "let a := "+strings.Repeat("-", 5000)+"1;", "Maximum nesting depth exceeded")
+	assertProgramNoError(t, "let a := "+strings.Repeat("(", 100)+"1"+strings.Repeat(")", 100)+";")
+
+	// every branch of an 'else if' or 'elif' chain is nested in the previous one
+	for _, branch := range []string{" else if false {}", " elif false {}"} {
+		assertProgramNoError(t, "if false {}"+strings.Repeat(branch, 500))
+		assertProgramErrorMessage(t, "if false {}"+strings.Repeat(branch, 20000), "Maximum nesting depth exceeded")
+	}
+	for _, branch := range []string{" else if false { 1 }", " elif false { 1 }"} {
+		assertProgramNoError(t, "let a := if false { 1 }"+strings.Repeat(branch, 500)+" else { 2 };")
+		assertProgramErrorMessage(t, "let a := if false { 1 }"+strings.Repeat(branch, 20000)+" else { 2 };",
+			"Maximum nesting depth exceeded")
+	}
+}
+
+func TestUnusedWarnings(t *testing.T) {
+	assertWarnings(t, "let a := 1;", "Unused variable 'a'")
+	assertWarnings(t, "let a := 1; let b := a;", "Unused variable 'b'")
+	assertWarnings(t, "let a := 1; a = 2;", "Unused variable 'a'")
+	assertWarnings(t, "fn f() int { let x := 1, y := 2; return x; }", "Unused variable 'y'")
+	assertWarnings(t, "let a := 1; fn f() int { return a; }")
+	assertWarnings(t, "let a := 1; a++; let _ := 2;")
+	assertWarnings(t, "let a := 1; while a < 10 { let b := 2; a += b; }")
+
+	theParser := New(lexer.FromCode("let a := 1;"))
+	_, errors := theParser.ParseProgram(types.NewContext())
+	assert.Equal(t, len(errors), 0)
+	assert.Equal(t, len(theParser.Warnings()), 0)
+}
+
+func assertWarnings(t *testing.T, input string, messages ...string) {
+	theParser := New(lexer.FromCode(input))
+	theParser.WarnUnused()
+	_, errors := theParser.ParseProgram(types.NewContext())
+	assert.Equal(t, len(errors), 0, input)
+
+	warningMessages := make([]string, 0)
+	for _, warning := range theParser.Warnings() {
+		warningMessages = append(warningMessages, warning.Message)
+	}
+	assert.DeepEqual(t, warningMessages, append([]string{}, messages...))
+}
+
+func TestBoundMethods(t *testing.T) {
+	assertProgramNoError(t, "fn (int)::plus(other: int) int { return this + other; } let f: fn(int) int = (1).plus; let a: int = f(2);")
+	assertProgramErrorMessage(t, "fn (int)::plus(other: int) int { return this + other; } let f: fn() int = (1).plus;",
+		"Type 'fn(int) int' is not assignable to 'fn() int'")
+	assertProgramErrorMessage(t, "fn (int)::plus(other: int) int { return this + other; } let f := (1).plus; f(\"a\");",
+		"Type 'string' is not assignable to 'int'")
+}
+
+func TestMissingReturn(t *testing.T) {
+	assertErrorMessage(t,
+		"fn test(a: int) int { if a > 0 { return 1; } }",
+		"Missing return statement: 'if' without 'else' does not return in all branches",
+	)
+	assertErrorMessage(t,
+		"fn test(a: int) int { if a > 0 { return 1; } else { a++; } }",
+		"Missing return statement: not all branches of 'if' return",
+	)
+	assertErrorMessage(t,
+		"fn test(a: int) int { while a > 0 { return 1; } }",
+		"Missing return statement after 'while' loop",
+	)
+	assertErrorMessage(t,
+		"fn test(a: int) int { a++; }",
+		"Missing return statement",
+	)
+	assertNoError(t, "fn test(a: int) int { if a > 0 { return 1; } else { return 2; } }")
+
+	parserErrors := parseProgram("fn test(a: int) int {\n\tif a > 0 {\n\t\treturn 1;\n\t} else if a < 0 {\n\t\treturn -1;\n\t}\n}")
+	assert.Equal(t, len(parserErrors), 1)
+	assert.Equal(t, parserErrors[0].Message, "Missing return statement: 'if' without 'else' does not return in all branches")
+	assert.Equal(t, parserErrors[0].Line, 4)
+	assert.Equal(t, parserErrors[0].Col, 9)
+
+	parserErrors = parseProgram("fn test(a: int) int { if a > 0 { return 1; } elif a < 0 { return -1; } elif a == 0 { a++; } }")
+	assert.Equal(t, len(parserErrors), 1)
+	assert.Equal(t, parserErrors[0].Message, "Missing return statement: not all branches of 'if' return")
+	assert.Equal(t, parserErrors[0].Line, 1)
+	assert.Equal(t, parserErrors[0].Col, 84)
+
+	// the first branch that does not return is reported, not the last 'if' of the chain
+	parserErrors = parseProgram("fn test(a: bool, b: bool) int {\n\tif a {\n\t\ta = b\n\t} else if b {\n\t\treturn 1\n\t} else {\n\t\treturn 2\n\t}\n}")
+	assert.Equal(t, len(parserErrors), 1)
+	assert.Equal(t, parserErrors[0].Message, "Missing return statement: not all branches of 'if' return")
+	assert.Equal(t, parserErrors[0].Line, 2)
+	assert.Equal(t, parserErrors[0].Col, 7)
+
+	parserErrors = parseProgram("fn test(a: bool, b: bool) int { if a { return 1 } else if b { return 2 } else { a = b } }")
+	assert.Equal(t, len(parserErrors), 1)
+	assert.Equal(t, parserErrors[0].Line, 1)
+	assert.Equal(t, parserErrors[0].Col, 79)
 }
 
 func assertStatement(t *testing.T, input string, expected Statement) {
@@ -81,7 +677,25 @@ func assertStatement(t *testing.T, input string, expected Statement) {
 		return true
 	})
 
-	assert.DeepEqual(t, statement, expected, ignoreTokens, ignoreContext)
+	assert.DeepEqual(t, statement, expected, ignoreTokens, ignoreContext, cmpopts.IgnoreUnexported(Identifier{}, CallExpression{}))
+}
+
+func assertSameStatement(t *testing.T, input string, expected string) {
+
+	parseWithA := func(input string) Statement {
+		context := types.NewContext()
+		context.DefineMemberType("a", &types.Int{})
+		return New(lexer.FromCode(input)).parseStatement(context)
+	}
+
+	ignoreTokens := cmp.Comparer(func(t1, t2 *token.Token) bool {
+		return true
+	})
+	ignoreContext := cmp.Comparer(func(c1, c2 *types.Context) bool {
+		return true
+	})
+
+	assert.DeepEqual(t, parseWithA(input), parseWithA(expected), ignoreTokens, ignoreContext, cmpopts.IgnoreUnexported(Identifier{}, CallExpression{}))
 }
 
 func parse(input string) *Parser {
@@ -92,11 +706,51 @@ func parse(input string) *Parser {
 	return theParser
 }
 
+func parseProgram(input string) []*errors.ParserError {
+	theLexer := lexer.FromCode(input)
+	theParser := New(theLexer)
+	_, errors := theParser.ParseProgram(types.NewContext())
+	return errors
+}
+
+func assertProgramError(t *testing.T, input string) {
+	assert.Assert(t, len(parseProgram(input)) > 0, input)
+}
+
+func assertProgramErrorMessage(t *testing.T, input string, message string) {
+	errors := parseProgram(input)
+	assert.Assert(t, len(errors) == 1, input)
+	assert.Equal(t, errors[0].Message, message)
+}
+
+func assertProgramNoError(t *testing.T, input string) {
+	errors := parseProgram(input)
+
+	errorMessages := make([]string, len(errors))
+	for i, err := range errors {
+		errorMessages[i] = err.Message
+	}
+
+	assert.Assert(t, len(errors) == 0, "\ninput: %s\nerrors: %v", input, errorMessages)
+}
+
 func assertError(t *testing.T, input string) {
 	theParser := parse(input)
 	assert.Assert(t, len(theParser.errors) > 0, input)
 }
 
+func assertErrorMessage(t *testing.T, input string, message string) {
+	theParser := parse(input)
+
+	errorMessages := make([]string, len(theParser.errors))
+	for i, err := range theParser.errors {
+		errorMessages[i] = err.Message
+	}
+
+	assert.Assert(t, len(theParser.errors) == 1, "\ninput: %s\nerrors: %v", input, errorMessages)
+	assert.Equal(t, errorMessages[0], message)
+}
+
 func assertNoError(t *testing.T, input string) {
 
 	theParser := parse(input)
@@ -5,6 +5,17 @@ import (
 	"bananascript/src/types"
 )
 
+var OperatorMethods = map[token.Type]string{
+	token.Plus:  "__add__",
+	token.Minus: "__sub__",
+	token.Star:  "__mul__",
+	token.Slash: "__div__",
+	token.LT:    "__lt__",
+	token.GT:    "__gt__",
+	token.LTE:   "__le__",
+	token.GTE:   "__ge__",
+}
+
 func (parser *Parser) getExpressionType(expression Expression, context *types.Context) types.Type {
 	switch expression := expression.(type) {
 	case *Identifier:
@@ -21,6 +32,10 @@ func (parser *Parser) getExpressionType(expression Expression, context *types.Co
 		return parser.getIncrementExpressionType(expression, context)
 	case *MemberAccessExpression:
 		return parser.getMemberAccessExpressionType(expression, context)
+	case *BlockExpression:
+		return expression.ValueType
+	case *IfExpression:
+		return expression.ValueType
 	case *StringLiteral:
 		return &types.String{}
 	case *IntegerLiteral:
@@ -41,11 +56,31 @@ func (parser *Parser) getExpressionType(expression Expression, context *types.Co
 }
 
 func (parser *Parser) getIdentifierType(identifier *Identifier, context *types.Context) types.Type {
-	theType, ok := context.GetMemberType(identifier.Value)
-	if !ok {
+	theType := parser.resolveIdentifierType(identifier, context)
+	if !isNever(theType) {
+		context.MarkUsed(identifier.Value)
+	}
+	return theType
+}
+
+// resolves the type of an identifier without counting it as a read, like the target of an assignment
+func (parser *Parser) resolveIdentifierType(identifier *Identifier, context *types.Context) types.Type {
+	if identifier.Value == types.Discard {
+		parser.error(identifier.IdentToken, "Cannot use '%s' as a value", types.Discard)
+		return &types.Never{}
+	}
+	theType, depth, ok := context.GetMemberTypeDepth(identifier.Value)
+	if !ok && parser.initializing[identifier.Value] > 0 {
+		parser.error(identifier.IdentToken, "Cannot reference '%s' in its own initializer", identifier.Value)
+		return &types.Never{}
+	} else if !ok {
 		parser.error(identifier.IdentToken, "Cannot resolve reference to '%s'", identifier.Value)
 		return &types.Never{}
 	}
+	identifier.depth, identifier.resolved = depth, true
+	if context.IsGlobal(identifier.Value) {
+		parser.referenceGlobal(identifier)
+	}
 	return theType
 }
 
@@ -59,7 +94,7 @@ func (parser *Parser) getPrefixExpressionType(prefixExpression *PrefixExpression
 	switch prefixExpression.Operator {
 	case token.Bang:
 		return &types.Bool{}
-	case token.Minus:
+	case token.Minus, token.Plus:
 		switch currentType.(type) {
 		case *types.Int:
 			return &types.Int{}
@@ -87,12 +122,28 @@ func (parser *Parser) getInfixExpressionType(infixExpression *InfixExpression, c
 	_, rightIsString := rightType.(*types.String)
 
 	switch infixExpression.Operator {
-	case token.EQ, token.NEQ, token.LogicalOr, token.LogicalAnd:
+	case token.EQ, token.NEQ:
+		return &types.Bool{}
+	case token.LogicalOr, token.LogicalAnd:
+		// any value can be converted to bool, except for void which does not exist at runtime
+		for _, operandType := range []types.Type{leftType, rightType} {
+			_, isBool := operandType.(*types.Bool)
+			_, isVoid := operandType.(*types.Void)
+			if isVoid || (parser.strict && !isBool) {
+				parser.error(infixExpression.OperatorToken, "Operator '%s' is not defined for '%s'",
+					infixExpression.Operator.OperatorString(), operandType.ToString())
+				return &types.Never{}
+			}
+		}
 		return &types.Bool{}
 	case token.LT, token.GT, token.LTE, token.GTE:
 		if (leftIsInt || leftIsFloat) && (rightIsInt || rightIsFloat) {
 			return &types.Bool{}
 		}
+	case token.In:
+		if leftIsString && rightIsString {
+			return &types.Bool{}
+		}
 	case token.Plus:
 		if leftIsString || rightIsString {
 			return &types.String{}
@@ -103,7 +154,7 @@ func (parser *Parser) getInfixExpressionType(infixExpression *InfixExpression, c
 				return &types.Float{}
 			}
 		}
-	case token.Minus, token.Slash, token.Star:
+	case token.Minus, token.Slash, token.Star, token.Percent, token.Power:
 		if (leftIsInt || leftIsFloat) && (rightIsInt || rightIsFloat) {
 			if leftIsInt && rightIsInt {
 				return &types.Int{}
@@ -113,17 +164,58 @@ func (parser *Parser) getInfixExpressionType(infixExpression *InfixExpression, c
 		}
 	}
 
+	if returnType, ok := getOperatorMethodType(infixExpression.Operator, leftType, rightType, context); ok {
+		return returnType
+	}
+
+	switch infixExpression.Operator {
+	case token.LT, token.GT, token.LTE, token.GTE:
+		for _, operandType := range []types.Type{leftType, rightType} {
+			switch operandType.(type) {
+			case *types.Bool, *types.Null, *types.Optional:
+				parser.error(infixExpression.OperatorToken, "Operator '%s' is not defined for '%s'",
+					infixExpression.Operator.OperatorString(), operandType.ToString())
+				return &types.Never{}
+			}
+		}
+	}
+
 	parser.error(infixExpression.OperatorToken, "Type mismatch: %s %s %s", leftType.ToString(),
-		infixExpression.Operator.ToString(), rightType.ToString())
+		infixExpression.Operator.OperatorString(), rightType.ToString())
 	return &types.Never{}
 }
 
+// operators that are not defined for the operand types fall back to a member function like __add__
+func getOperatorMethodType(operator token.Type, leftType types.Type, rightType types.Type, context *types.Context) (types.Type, bool) {
+	methodName, ok := OperatorMethods[operator]
+	if !ok {
+		return nil, false
+	}
+	memberType, _, ok := context.GetTypeMemberType(methodName, leftType)
+	if !ok {
+		return nil, false
+	}
+	functionType, isFunction := memberType.(*types.Function)
+	if !isFunction || len(functionType.ParameterTypes) != 1 || !functionType.ParameterTypes[0].IsAssignable(rightType, context) {
+		return nil, false
+	}
+	if _, isVoid := functionType.ReturnType.(*types.Void); isVoid {
+		return nil, false
+	}
+	return functionType.ReturnType, true
+}
+
 func (parser *Parser) getAssignmentExpressionType(assignmentExpression *AssignmentExpression, context *types.Context) types.Type {
-	leftType, rightType := parser.getExpressionType(assignmentExpression.Name, context), parser.getExpressionType(assignmentExpression.Expression, context)
+	leftType, rightType := parser.resolveIdentifierType(assignmentExpression.Name, context), parser.getExpressionType(assignmentExpression.Expression, context)
 	if isNever(leftType) || isNever(rightType) {
 		return &types.Never{}
 	}
 
+	if context.IsBuiltin(assignmentExpression.Name.Value) {
+		parser.error(assignmentExpression.IdentToken, "Cannot assign to builtin '%s'", assignmentExpression.Name.Value)
+		return &types.Never{}
+	}
+
 	if !leftType.IsAssignable(rightType, context) {
 		parser.error(assignmentExpression.AssignToken, "Type '%s' is not assignable to '%s'",
 			rightType.ToString(), leftType.ToString())
@@ -139,30 +231,59 @@ func (parser *Parser) getCallExpressionType(callExpression *CallExpression, cont
 	case *types.Never:
 		return &types.Never{}
 	case *types.Function:
-		if len(functionType.ParameterTypes) == len(callExpression.Arguments) {
-			for i, parameterType := range functionType.ParameterTypes {
-				if isNever(parameterType) {
-					continue
-				}
-				argumentType := parser.getExpressionType(callExpression.Arguments[i], context)
-				if !isNever(argumentType) && !parameterType.IsAssignable(argumentType, context) {
-					parser.error(callExpression.Arguments[i].Token(), "Type '%s' is not assignable to '%s'",
-						argumentType.ToString(), parameterType.ToString())
-				}
+		return parser.checkCallArguments(callExpression, functionType, nil, context)
+	case *types.Generic:
+		argumentTypes := make([]types.Type, len(callExpression.Arguments))
+		for i, argument := range callExpression.Arguments {
+			argumentTypes[i] = parser.getExpressionType(argument, context)
+			if isNever(argumentTypes[i]) {
+				return &types.Never{}
 			}
-		} else {
-			parser.error(callExpression.ParenToken, "Mismatching amount of arguments (%d vs %d)",
-				len(callExpression.Arguments), len(functionType.ParameterTypes))
 		}
-		return functionType.ReturnType
+		resolvedType, err := functionType.Resolve(argumentTypes, context)
+		if err != nil {
+			parser.error(callExpression.ParenToken, "%s", err.Error())
+			return &types.Never{}
+		}
+		return parser.checkCallArguments(callExpression, resolvedType, argumentTypes, context)
 	default:
 		parser.error(callExpression.ParenToken, "Cannot call '%s'", functionType.ToString())
 		return &types.Never{}
 	}
 }
 
+// argumentTypes are the types of the arguments if they have already been checked, otherwise nil
+func (parser *Parser) checkCallArguments(callExpression *CallExpression, functionType *types.Function, argumentTypes []types.Type, context *types.Context) types.Type {
+	callExpression.functionType = functionType
+	if len(functionType.ParameterTypes) == len(callExpression.Arguments) {
+		for i, parameterType := range functionType.ParameterTypes {
+			if isNever(parameterType) {
+				continue
+			}
+			var argumentType types.Type
+			if argumentTypes != nil {
+				argumentType = argumentTypes[i]
+			} else {
+				argumentType = parser.getExpressionType(callExpression.Arguments[i], context)
+			}
+			if !isNever(argumentType) && !parameterType.IsAssignable(argumentType, context) {
+				parser.error(callExpression.Arguments[i].Token(), "Type '%s' is not assignable to '%s'",
+					argumentType.ToString(), parameterType.ToString())
+			}
+		}
+	} else {
+		parser.error(callExpression.ParenToken, "Mismatching amount of arguments (%d vs %d)",
+			len(callExpression.Arguments), len(functionType.ParameterTypes))
+	}
+	return functionType.ReturnType
+}
+
 func (parser *Parser) getIncrementExpressionType(incrementExpression *IncrementExpression, context *types.Context) types.Type {
 	identType := parser.getExpressionType(incrementExpression.Name, context)
+	if context.IsBuiltin(incrementExpression.Name.Value) {
+		parser.error(incrementExpression.Name.IdentToken, "Cannot assign to builtin '%s'", incrementExpression.Name.Value)
+		return &types.Never{}
+	}
 	switch identType.(type) {
 	case *types.Never, *types.Int, *types.Float:
 		return identType
@@ -180,6 +301,14 @@ func (parser *Parser) getMemberAccessExpressionType(memberAccessExpression *Memb
 	return memberAccessExpression.MemberType
 }
 
+// for expressions that have already been checked, so that errors are not reported twice
+func (parser *Parser) getExpressionTypeSilently(expression Expression, context *types.Context) types.Type {
+	errorCount := len(parser.errors)
+	theType := parser.getExpressionType(expression, context)
+	parser.errors = parser.errors[:errorCount]
+	return theType
+}
+
 func isNever(theType types.Type) bool {
 	_, isNever := theType.(*types.Never)
 	return isNever
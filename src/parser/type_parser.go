@@ -37,12 +37,18 @@ func (parser *Parser) registerTypeParseFunctions() {
 }
 
 func (parser *Parser) parseType(context *types.Context, precedence TypePrecedence) types.Type {
+	if !parser.enter() {
+		parser.leave()
+		return &types.Never{}
+	}
+
 	currentToken := parser.current()
 	prefixFunction := prefixTypeParseFunctions[currentToken.Type]
 	if prefixFunction == nil {
 		if currentToken.Type != token.Illegal {
 			parser.error(currentToken, "Unexpected %s", currentToken.ToString())
 		}
+		parser.leave()
 		return &types.Never{}
 	}
 
@@ -58,6 +64,7 @@ func (parser *Parser) parseType(context *types.Context, precedence TypePrecedenc
 		theType = infixFunction(context, theType)
 	}
 
+	parser.leave()
 	return theType
 }
 
@@ -43,6 +43,24 @@ func TestTypeParser(t *testing.T) {
 	)
 }
 
+func TestTypeToString(t *testing.T) {
+	inputs := []string{
+		"fn() void",
+		"fn(int, string) bool",
+		"fn(int?) string?",
+		"fn(fn(int) void, float) fn() fn() null",
+	}
+	for _, input := range inputs {
+		theType := New(lexer.FromCode(input)).parseType(types.NewContext(), TypeLowest)
+		assert.Equal(t, theType.ToString(), input)
+	}
+
+	assertErrorMessage(t, "{ fn f(a: int) string { return \"\"; } let g: fn(string) string = f; }",
+		"Type 'fn(int) string' is not assignable to 'fn(string) string'")
+	assertErrorMessage(t, "{ fn f(callback: fn(int) void) {} f(1); }", "Type 'int' is not assignable to 'fn(int) void'")
+	assertErrorMessage(t, "{ fn f() fn() void { return 1; } }", "Type 'int' is not assignable to 'fn() void'")
+}
+
 func assertType(t *testing.T, input string, expected types.Type) {
 
 	theLexer := lexer.FromCode(input)
@@ -7,25 +7,38 @@ import (
 	"bananascript/src/parser"
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 )
 
 const PROMPT = "> "
 
 func Start() {
-	scanner := bufio.NewScanner(os.Stdin)
+	Run(os.Stdin, os.Stdout)
+}
+
+// reads statements line by line from input and writes prompts, results and errors to output
+func Run(input io.Reader, output io.Writer) {
+	scanner := bufio.NewScanner(input)
 	context, environment := builtins.NewContextAndEnvironment()
+	printers := builtinPrinters(environment)
+
+	previousOutput := builtins.Output
+	builtins.Output = output
+	defer func() {
+		builtins.Output = previousOutput
+	}()
 
 	for {
-		fmt.Printf(PROMPT)
+		_, _ = fmt.Fprint(output, PROMPT)
 
 		if !scanner.Scan() {
 			return
 		}
 
-		input := scanner.Text() + ";"
+		line := scanner.Text() + ";"
 
-		theLexer := lexer.FromCode(input)
+		theLexer := lexer.FromCode(line)
 		theParser := parser.New(theLexer)
 
 		program, errors := theParser.ParseProgram(context)
@@ -34,15 +47,15 @@ func Start() {
 
 		if len(errors) > 0 {
 			for _, err := range errors {
-				fmt.Println(err.PrettyPrint(false))
+				_, _ = fmt.Fprintln(output, err.PrettyPrint(false))
 			}
 		} else {
-			var result evaluator.Object = nil
-			for _, statement := range program.Statements {
-				result = evaluator.Eval(statement, newEnvironment)
+			result := evaluator.EvalStatements(program.Statements, newEnvironment)
+			if exit, isExit := result.(*evaluator.ExitObject); isExit {
+				os.Exit(int(exit.Code))
 			}
-			if result != nil {
-				fmt.Println(result.ToString())
+			if result != nil && !endsWithPrint(program.Statements, newEnvironment, printers) {
+				_, _ = fmt.Fprintln(output, result.ToString())
 			}
 			if _, isError := result.(*evaluator.ErrorObject); !isError {
 				context = newContext
@@ -51,3 +64,42 @@ func Start() {
 		}
 	}
 }
+
+func builtinPrinters(environment *evaluator.Environment) []evaluator.Object {
+	printers := make([]evaluator.Object, 0)
+	for _, name := range []string{"print", "println"} {
+		if printer, exists := environment.GetObject(name); exists {
+			printers = append(printers, printer)
+		}
+	}
+	return printers
+}
+
+// print and println return their argument, which has already been written and must not be echoed again
+func endsWithPrint(statements []parser.Statement, environment *evaluator.Environment, printers []evaluator.Object) bool {
+	if len(statements) == 0 {
+		return false
+	}
+	statement, isExpression := statements[len(statements)-1].(*parser.ExpressionStatement)
+	if !isExpression {
+		return false
+	}
+	call, isCall := statement.Expression.(*parser.CallExpression)
+	if !isCall {
+		return false
+	}
+	identifier, isIdentifier := call.Function.(*parser.Identifier)
+	if !isIdentifier {
+		return false
+	}
+	function, exists := environment.GetObject(identifier.Value)
+	if !exists {
+		return false
+	}
+	for _, printer := range printers {
+		if evaluator.ObjectsEqual(function, printer) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package repl
+
+import (
+	"bytes"
+	"gotest.tools/assert"
+	"strings"
+	"testing"
+)
+
+func TestPrintIsNotEchoed(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`println("hi")`, "> hi\n> "},
+		{`println("hi");`, "> hi\n> "},
+		{`print("hi")`, "> hi> "},
+		{`1 + 2`, "> 3\n> "},
+		{`let x := println("hi") + "!"; x`, "> hi\nhi!\n> "},
+		{`let p := println; p("hi")`, "> hi\n> "},
+		{`fn shout(s: string) string { println(s); return s + "!"; }` + "\n" + `shout("hi")`, "> > hi\nhi!\n> "},
+	}
+
+	for _, test := range tests {
+		output := &bytes.Buffer{}
+		Run(strings.NewReader(test.input), output)
+		assert.Equal(t, output.String(), test.expected, test.input)
+	}
+}
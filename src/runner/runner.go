@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"bananascript/src/builtins"
+	"bananascript/src/errors"
+	"bananascript/src/evaluator"
+	"bananascript/src/lexer"
+	"bananascript/src/parser"
+	"bananascript/src/types"
+	"fmt"
+	"github.com/gookit/color"
+	"io"
+)
+
+func Run(fileName string, optimize bool, warnUnused bool, output io.Writer) int {
+	context, environment := builtins.NewContextAndEnvironment()
+	program, source, ok := parseFile(fileName, context, warnUnused, output)
+	if !ok {
+		return 1
+	}
+
+	if optimize {
+		parser.FoldConstants(program)
+	}
+
+	programEnvironment := evaluator.ExtendEnvironment(environment, program.Context)
+	object := evaluator.EvalStatements(program.Statements, programEnvironment)
+	switch object := object.(type) {
+	case *evaluator.ErrorObject:
+		printRuntimeError(object, source, output)
+		return 1
+	case *evaluator.ExitObject:
+		return int(object.Code)
+	}
+
+	exitCode, err := runMain(programEnvironment)
+	if err != nil {
+		printRuntimeError(err, source, output)
+		return 1
+	}
+	return exitCode
+}
+
+// calls the main function if the program defines one, the parser has already checked its signature
+func runMain(environment *evaluator.Environment) (int, *evaluator.ErrorObject) {
+	main, exists := environment.GetObjectStrict("main")
+	if !exists {
+		return 0, nil
+	}
+	function, isFunction := main.(evaluator.Function)
+	if !isFunction {
+		return 0, nil
+	}
+
+	result := function.Execute([]evaluator.Object{})
+	if returned, isReturn := result.(*evaluator.ReturnObject); isReturn {
+		result = returned.Object
+	}
+	switch result := result.(type) {
+	case *evaluator.ErrorObject:
+		return 1, result
+	case *evaluator.ExitObject:
+		return int(result.Code), nil
+	case *evaluator.IntegerObject:
+		return int(result.Value), nil
+	default:
+		return 0, nil
+	}
+}
+
+func Test(fileName string, output io.Writer) int {
+	context, environment := builtins.NewContextAndEnvironment()
+
+	assertions := 0
+	assertBuiltin, _ := environment.GetObject("assert")
+	environment.DefineObject("assert", &builtins.BuiltinFunction{
+		FunctionType: assertBuiltin.Type(),
+		Executor: func(this evaluator.Object, arguments []evaluator.Object) evaluator.Object {
+			assertions++
+			return assertBuiltin.(evaluator.Function).Execute(arguments)
+		},
+	})
+
+	program, source, ok := parseFile(fileName, context, false, output)
+	if !ok {
+		return 1
+	}
+
+	object := evaluator.Eval(program, environment)
+	if exit, isExit := object.(*evaluator.ExitObject); isExit {
+		_, _ = fmt.Fprintln(output, color.FgRed.Sprintf("Exited with code %d after %d assertion(s)", exit.Code, assertions))
+		return int(exit.Code)
+	}
+	if err, isError := object.(*evaluator.ErrorObject); isError {
+		printRuntimeError(err, source, output)
+		_, _ = fmt.Fprintln(output, color.FgRed.Sprintf("Failed after %d assertion(s)", assertions))
+		return 1
+	}
+
+	_, _ = fmt.Fprintln(output, color.FgGreen.Sprintf("%d assertion(s) passed", assertions))
+	return 0
+}
+
+// parses the file and reports errors to output, warnings are only reported if the program has no errors
+func parseFile(fileName string, context *types.Context, warnUnused bool, output io.Writer) (*parser.Program, string, bool) {
+	theLexer, err := lexer.FromFile(fileName)
+	if err != nil {
+		_, _ = fmt.Fprintln(output, err.Error())
+		return nil, "", false
+	}
+	source := theLexer.Source()
+
+	theParser := parser.New(theLexer)
+	if warnUnused {
+		theParser.WarnUnused()
+	}
+	program, parserErrors := theParser.ParseProgram(context)
+	if len(parserErrors) > 0 {
+		errorStr := "Encountered %d error"
+		if len(parserErrors) > 1 {
+			errorStr += "s"
+		}
+		errorStr += ":"
+		_, _ = fmt.Fprintln(output, color.FgRed.Sprintf(errorStr, len(parserErrors)))
+		for _, err := range parserErrors {
+			_, _ = fmt.Fprintln(output, errors.FormatError(source, err))
+		}
+		return nil, "", false
+	}
+	for _, warning := range theParser.Warnings() {
+		_, _ = fmt.Fprintln(output, errors.FormatWarning(source, warning))
+	}
+	return program, source, true
+}
+
+func printRuntimeError(err *evaluator.ErrorObject, source string, output io.Writer) {
+	if err.Token == nil {
+		_, _ = fmt.Fprintln(output, errors.New(0, 0, nil, "%s", err.Message).PrettyPrint(false))
+		return
+	}
+	_, _ = fmt.Fprintln(output, errors.FormatError(source, errors.NewFromToken(err.Token, "%s", err.Message)))
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"bananascript/src/evaluator"
+	"bytes"
+	"fmt"
+	"github.com/gookit/color"
+	"gotest.tools/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTest(t *testing.T) {
+
+	output := &bytes.Buffer{}
+	assert.Equal(t, Test("testdata/passing.banana", output), 0)
+	assert.Assert(t, strings.Contains(output.String(), "2 assertion(s) passed"), output.String())
+
+	output = &bytes.Buffer{}
+	assert.Equal(t, Test("testdata/failing.banana", output), 1)
+	assert.Assert(t, strings.Contains(output.String(), "Assertion failed: ((square(2)) == 5)"), output.String())
+	assert.Assert(t, strings.Contains(output.String(), "failing.banana:6:1"), output.String())
+	assert.Assert(t, strings.Contains(output.String(), "\tassert(square(2) == 5);\n\t^"), output.String())
+	assert.Assert(t, strings.Contains(output.String(), "Failed after 2 assertion(s)"), output.String())
+}
+
+func TestMainFunction(t *testing.T) {
+	output := &bytes.Buffer{}
+	assert.Equal(t, Run("testdata/main.banana", false, false, output), 3)
+	assert.Equal(t, output.String(), "")
+
+	output = &bytes.Buffer{}
+	assert.Equal(t, Run("testdata/passing.banana", false, false, output), 0)
+}
+
+func TestEmptyProgram(t *testing.T) {
+	inputs := []string{"", "   ", "\n\t\r\n", "// just a comment", "/* block */\n// line", "#!/usr/bin/env bananascript\n"}
+
+	for i, input := range inputs {
+		fileName := filepath.Join(t.TempDir(), fmt.Sprintf("empty%d.banana", i))
+		assert.NilError(t, os.WriteFile(fileName, []byte(input), 0644))
+
+		output := &bytes.Buffer{}
+		assert.Equal(t, Run(fileName, false, false, output), 0, "%q", input)
+		assert.Equal(t, output.String(), "", "%q", input)
+
+		output = &bytes.Buffer{}
+		assert.Equal(t, Test(fileName, output), 0, "%q", input)
+		assert.Assert(t, strings.Contains(output.String(), "0 assertion(s) passed"), output.String())
+	}
+}
+
+func TestWarnUnused(t *testing.T) {
+	color.Disable()
+	fileName := filepath.Join(t.TempDir(), "unused.banana")
+	assert.NilError(t, os.WriteFile(fileName, []byte("let a := 1;\nlet b := 2;\nexit(b);"), 0644))
+
+	output := &bytes.Buffer{}
+	assert.Equal(t, Run(fileName, false, true, output), 2)
+	assert.Equal(t, output.String(), "Warning: Unused variable 'a'\n\tin "+fileName+":1:5\n\tlet a := 1;\n\t    ^\n")
+
+	output = &bytes.Buffer{}
+	assert.Equal(t, Run(fileName, false, false, output), 2)
+	assert.Equal(t, output.String(), "")
+}
+
+func TestExit(t *testing.T) {
+	output := &bytes.Buffer{}
+	assert.Equal(t, Run("testdata/exit.banana", false, false, output), 4)
+	assert.Equal(t, output.String(), "")
+}
+
+func TestRuntimeError(t *testing.T) {
+	color.Disable()
+	fileName := filepath.Join(t.TempDir(), "error.banana")
+	assert.NilError(t, os.WriteFile(fileName, []byte("let a := 1;\nlet b := a / 0;"), 0644))
+
+	output := &bytes.Buffer{}
+	assert.Equal(t, Run(fileName, false, false, output), 1)
+	assert.Equal(t, output.String(), "Error: Division by zero\n\tin "+fileName+":2:12\n\tlet b := a / 0;\n\t           ^\n")
+
+	output = &bytes.Buffer{}
+	printRuntimeError(evaluator.NewError("Maximum nesting depth exceeded"), "", output)
+	assert.Equal(t, output.String(), "Error: Maximum nesting depth exceeded\n")
+}
@@ -34,11 +34,19 @@ const (
 	Minus
 	Slash
 	Star
+	Percent
+	Power
 
 	LogicalAnd
 	LogicalOr
 
 	Assign
+	PlusAssign
+	MinusAssign
+	StarAssign
+	SlashAssign
+	PercentAssign
+	PowerAssign
 	Qmark
 	Amp
 	Bang
@@ -51,6 +59,7 @@ const (
 	Colon
 	DoubleColon
 	Define
+	At
 
 	LParen
 	RParen
@@ -65,8 +74,12 @@ const (
 	Const
 	If
 	Else
+	Elif
 	For
 	While
+	Break
+	Continue
+	In
 
 	True
 	False
@@ -75,20 +88,24 @@ const (
 )
 
 var Keywords = map[string]Type{
-	"fn":     Func,
-	"return": Return,
-	"let":    Let,
-	"const":  Const,
-	"true":   True,
-	"false":  False,
-	"null":   Null,
-	"void":   Void,
-	"if":     If,
-	"else":   Else,
-	"for":    For,
-	"while":  While,
-	"type":   TypeDef,
-	"iface":  Iface,
+	"fn":       Func,
+	"return":   Return,
+	"let":      Let,
+	"const":    Const,
+	"true":     True,
+	"false":    False,
+	"null":     Null,
+	"void":     Void,
+	"if":       If,
+	"else":     Else,
+	"elif":     Elif,
+	"for":      For,
+	"while":    While,
+	"break":    Break,
+	"continue": Continue,
+	"in":       In,
+	"type":     TypeDef,
+	"iface":    Iface,
 }
 
 func (token Token) ToString() string {
@@ -113,9 +130,17 @@ func (tokenType Type) ToString() string {
 		"-",
 		"/",
 		"*",
+		"%",
+		"**",
 		"&&",
 		"||",
 		"=",
+		"+=",
+		"-=",
+		"*=",
+		"/=",
+		"%=",
+		"**=",
 		"?",
 		"&",
 		"!",
@@ -127,6 +152,7 @@ func (tokenType Type) ToString() string {
 		":",
 		"::",
 		":=",
+		"@",
 		"(",
 		")",
 		"{",
@@ -139,8 +165,12 @@ func (tokenType Type) ToString() string {
 		"CONST",
 		"IF",
 		"ELSE",
+		"ELIF",
 		"FOR",
 		"WHILE",
+		"BREAK",
+		"CONTINUE",
+		"IN",
 		"TRUE",
 		"FALSE",
 		"NULL",
@@ -148,6 +178,14 @@ func (tokenType Type) ToString() string {
 	}[tokenType]
 }
 
+// the operator as it is written in the source, keywords like 'in' are lowercase unlike in ToString
+func (tokenType Type) OperatorString() string {
+	if tokenType == In {
+		return "in"
+	}
+	return tokenType.ToString()
+}
+
 func (tokenType Type) ToStringHumanReadable() string {
 	return [...]string{
 		"illegal token",
@@ -166,9 +204,17 @@ func (tokenType Type) ToStringHumanReadable() string {
 		"'-'",
 		"'/'",
 		"'*'",
+		"'%'",
+		"'**'",
 		"'&&'",
 		"'||'",
 		"'='",
+		"'+='",
+		"'-='",
+		"'*='",
+		"'/='",
+		"'%='",
+		"'**='",
 		"'?'",
 		"'&'",
 		"'!'",
@@ -180,6 +226,7 @@ func (tokenType Type) ToStringHumanReadable() string {
 		"':'",
 		"'::'",
 		"':='",
+		"'@'",
 		"'('",
 		"')'",
 		"'{'",
@@ -192,8 +239,12 @@ func (tokenType Type) ToStringHumanReadable() string {
 		"'const'",
 		"'if'",
 		"'else'",
+		"'elif'",
 		"'for'",
 		"'while'",
+		"'break'",
+		"'continue'",
+		"'in'",
 		"'true'",
 		"'false'",
 		"'null'",
@@ -4,12 +4,18 @@ import (
 	"reflect"
 )
 
+const Discard = "_"
+
 type Context struct {
 	parent       *Context
 	typeContexts map[Type]*Context
 	memberStore  map[string]Type
 	typeStore    map[string]Type
+	used         map[string]bool
 	ReturnType   Type
+	Builtins     bool // members are builtins, they cannot be redefined in a global context
+	Global       bool // top-level scope of a program
+	checking     *assignableCheck
 }
 
 func NewContext() *Context {
@@ -17,6 +23,7 @@ func NewContext() *Context {
 		typeContexts: make(map[Type]*Context),
 		memberStore:  make(map[string]Type),
 		typeStore:    make(map[string]Type),
+		used:         make(map[string]bool),
 	}
 }
 
@@ -27,6 +34,7 @@ func ExtendContext(parent *Context) *Context {
 		typeContexts: make(map[Type]*Context),
 		memberStore:  make(map[string]Type),
 		typeStore:    make(map[string]Type),
+		used:         make(map[string]bool),
 	}
 }
 
@@ -55,10 +63,29 @@ func CloneContext(context *Context) *Context {
 	return &Context{
 		parent:       context.parent,
 		ReturnType:   context.ReturnType,
+		Builtins:     context.Builtins,
+		Global:       context.Global,
 		typeContexts: cloneTypeMap(context.typeContexts),
 		memberStore:  cloneMap(context.memberStore),
 		typeStore:    cloneMap(context.typeStore),
+		used:         context.used, // reads in the clone count for the original
+	}
+}
+
+// returns a copy of the definitions made directly in this context, to be passed to Restore later
+func (context *Context) Snapshot() *Context {
+	snapshot := CloneContext(context)
+	for parentType, typeContext := range snapshot.typeContexts {
+		snapshot.typeContexts[parentType] = CloneContext(typeContext)
 	}
+	return snapshot
+}
+
+func (context *Context) Restore(snapshot *Context) {
+	restored := snapshot.Snapshot()
+	context.typeContexts = restored.typeContexts
+	context.memberStore = restored.memberStore
+	context.typeStore = restored.typeStore
 }
 
 func (context *Context) GetMemberTypeStrict(name string) (Type, bool) {
@@ -74,8 +101,59 @@ func (context *Context) GetMemberType(name string) (Type, bool) {
 	return memberType, ok
 }
 
+func (context *Context) GetMemberTypeDepth(name string) (Type, int, bool) {
+	depth := 0
+	for currentContext := context; currentContext != nil; currentContext = currentContext.parent {
+		if memberType, ok := currentContext.GetMemberTypeStrict(name); ok {
+			return memberType, depth, true
+		}
+		depth++
+	}
+	return nil, 0, false
+}
+
+// records that a member was read, in the context that defines it
+func (context *Context) MarkUsed(name string) {
+	for currentContext := context; currentContext != nil; currentContext = currentContext.parent {
+		if _, ok := currentContext.GetMemberTypeStrict(name); ok {
+			if currentContext.used == nil {
+				currentContext.used = make(map[string]bool)
+			}
+			currentContext.used[name] = true
+			return
+		}
+	}
+}
+
+// builtins can be shadowed in inner scopes, but not redefined globally
+func (context *Context) IsBuiltin(name string) bool {
+	for currentContext := context; currentContext != nil; currentContext = currentContext.parent {
+		if _, ok := currentContext.GetMemberTypeStrict(name); ok {
+			return currentContext.Builtins
+		}
+	}
+	return false
+}
+
+// whether the member is defined in the top-level scope of a program
+func (context *Context) IsGlobal(name string) bool {
+	for currentContext := context; currentContext != nil; currentContext = currentContext.parent {
+		if _, ok := currentContext.GetMemberTypeStrict(name); ok {
+			return currentContext.Global
+		}
+	}
+	return false
+}
+
+func (context *Context) IsUsed(name string) bool {
+	return context.used[name]
+}
+
 func (context *Context) DefineMemberType(name string, memberType Type) (Type, bool) {
-	if _, exists := context.GetMemberTypeStrict(name); exists {
+	if name == Discard {
+		return memberType, true
+	}
+	if _, exists := context.GetMemberTypeStrict(name); exists || (context.Global && context.IsBuiltin(name)) {
 		return nil, false
 	}
 	context.memberStore[name] = memberType
@@ -95,11 +173,25 @@ func (context *Context) GetTypeMemberTypeStrict(name string, parentType Type) (T
 func (context *Context) GetTypeMemberType(name string, parentType Type) (Type, Type, bool) {
 	memberType, resolvedParentType, ok := context.GetTypeMemberTypeStrict(name, parentType)
 	if !ok && context.parent != nil {
-		return context.parent.GetTypeMemberType(name, parentType)
+		return context.parent.withChecking(context.checking).GetTypeMemberType(name, parentType)
+	}
+	if iface, isIface := parentType.(*Iface); !ok && isIface {
+		memberType, ok = iface.Members[name]
+		return memberType, iface, ok
 	}
 	return memberType, resolvedParentType, ok
 }
 
+// a view of this context that remembers which interfaces are being checked for assignability
+func (context *Context) withChecking(checking *assignableCheck) *Context {
+	if context == nil || context.checking == checking {
+		return context
+	}
+	view := *context
+	view.checking = checking
+	return &view
+}
+
 func (context *Context) DefineTypeMemberType(name string, memberType Type, parentType Type) (Type, bool) {
 	_, _, exists := context.GetTypeMemberTypeStrict(name, parentType)
 	if exists {
@@ -128,6 +128,25 @@ func (functionType *Function) IsAssignable(other Type, context *Context) bool {
 	return false
 }
 
+// returns the type of the function with its first parameter fixed
+func (functionType *Function) Bind() *Function {
+	return &Function{ParameterTypes: functionType.ParameterTypes[1:], ReturnType: functionType.ReturnType}
+}
+
+// the type of a builtin function whose signature depends on the types of its arguments
+type Generic struct {
+	Signature string
+	Resolve   func(argumentTypes []Type, context *Context) (*Function, error)
+}
+
+func (generic *Generic) ToString() string {
+	return generic.Signature
+}
+
+func (generic *Generic) IsAssignable(other Type, _ *Context) bool {
+	return generic == other
+}
+
 type Optional struct {
 	Base Type
 }
@@ -147,11 +166,32 @@ func (optional *Optional) IsAssignable(other Type, context *Context) bool {
 	}
 }
 
+// named interfaces come from type definitions, they can refer to themselves in their members
 type Iface struct {
+	Name    string
 	Members map[string]Type
 }
 
+// pairs of interface and type that are being checked, so recursive interfaces do not check forever
+type assignableCheck struct {
+	iface *Iface
+	other Type
+	next  *assignableCheck
+}
+
+func (check *assignableCheck) contains(iface *Iface, other Type) bool {
+	for current := check; current != nil; current = current.next {
+		if current.iface == iface && current.other == other {
+			return true
+		}
+	}
+	return false
+}
+
 func (iface *Iface) ToString() string {
+	if iface.Name != "" {
+		return iface.Name
+	}
 	result := "iface { "
 	for name, memberType := range iface.Members {
 		result += name + ": " + memberType.ToString() + "; "
@@ -160,9 +200,17 @@ func (iface *Iface) ToString() string {
 }
 
 func (iface *Iface) IsAssignable(other Type, context *Context) bool {
+	var checking *assignableCheck
+	if context != nil {
+		checking = context.checking
+	}
+	if iface == other || checking.contains(iface, other) {
+		return true
+	}
+	checkingContext := context.withChecking(&assignableCheck{iface: iface, other: other, next: checking})
 	for name, memberType := range iface.Members {
-		actualType, _, ok := context.GetTypeMemberType(name, other)
-		if !ok || !memberType.IsAssignable(actualType, context) {
+		actualType, _, ok := checkingContext.GetTypeMemberType(name, other)
+		if !ok || !memberType.IsAssignable(actualType, checkingContext) {
 			return false
 		}
 	}